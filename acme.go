@@ -2,11 +2,15 @@ package main
 
 import (
 	"bytes"
-	"encoding/json"
+	"context"
 	"errors"
+	"fmt"
 	"io"
 	"os"
 	"path"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"9fans.net/go/acme"
@@ -18,11 +22,21 @@ type Win struct {
 	file string
 	acme *acme.Win
 	tag  string
-	c    *lsp.Client
-	f    *outline.File
+	srv  *Server
+	lang *language
+
+	// echoes holds changes that w made to its body but acme hasn't reported yet.
+	// If putOnEcho is set, w saves its body after all of them are reported.
+	echoes    []echo
+	putOnEcho bool
+
+	mu sync.Mutex // protects f
+	f  *outline.File
+
+	version int64 // version of the document; accessed atomically
 }
 
-func OpenFile(id int, file string, c *lsp.Client) (*Win, error) {
+func OpenFile(id int, file string, lang *language, srv *Server) (*Win, error) {
 	p, err := acme.Open(id, nil)
 	if err != nil {
 		time.Sleep(10 * time.Millisecond)
@@ -34,8 +48,9 @@ func OpenFile(id int, file string, c *lsp.Client) (*Win, error) {
 	w := Win{
 		file: file,
 		acme: p,
-		tag:  "Ref Doc",
-		c:    c,
+		tag:  "Ref Impl Typedef Decl Doc Hover Complete Sig Rename Fmt Action Symbols Callers Callees Types Occur Next",
+		srv:  srv,
+		lang: lang,
 	}
 
 	body, err := w.acme.ReadAll("body")
@@ -86,28 +101,52 @@ func (w *Win) setTag(isDirty bool) error {
 
 func (w *Win) DocumentID() lsp.TextDocumentIdentifier {
 	return lsp.TextDocumentIdentifier{
-		URI: w.c.URL(w.file),
+		URI: w.srv.Client().URL(w.file),
 	}
 }
 
+// Version returns the version of the document in w.
+// It is incremented whenever the body of w is changed.
+func (w *Win) Version() int {
+	return int(atomic.LoadInt64(&w.version))
+}
+
 func (w *Win) didOpenFile(body []byte) error {
-	return w.c.DidOpenTextDocument(&lsp.DidOpenTextDocumentParams{
+	atomic.StoreInt64(&w.version, 1)
+	return w.srv.Client().DidOpenTextDocument(&lsp.DidOpenTextDocumentParams{
 		TextDocument: lsp.TextDocumentItem{
-			URI:        w.c.URL(w.file),
-			LanguageID: "go",
+			URI:        w.srv.Client().URL(w.file),
+			LanguageID: w.lang.ID,
 			Version:    1,
 			Text:       string(body),
 		},
 	})
 }
 
+// Reopen sends the current body of w to the server as a newly opened document.
+// It is used to restore the state of the server after restarting.
+func (w *Win) Reopen() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	body, err := w.acme.ReadAll("body")
+	if err != nil {
+		return err
+	}
+	f, err := outline.NewFile(bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	w.f = f
+	return w.didOpenFile(body)
+}
+
 func (w *Win) Reload() error {
 	// TODO(lufia): reload file content
 	return nil
 }
 
 func (w *Win) didSave() error {
-	return w.c.DidSaveTextDocument(&lsp.DidSaveTextDocumentParams{
+	return w.srv.Client().DidSaveTextDocument(&lsp.DidSaveTextDocumentParams{
 		TextDocument: w.DocumentID(),
 	})
 }
@@ -116,23 +155,39 @@ func (w *Win) watch() {
 	for e := range w.acme.EventChan() {
 		if err := w.handleEvent(e); err != nil {
 			w.acme.Errf("%v", err)
-			continue
 		}
 	}
 }
 
 func (w *Win) handleEvent(e *acme.Event) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
 	p0 := outline.Pos(e.Q0)
 	p1 := outline.Pos(e.Q1)
-	s := string(e.Text)
 	switch e.C2 {
 	case 'I':
 		w.setTag(true)
+		s, err := w.eventText(e)
+		if err != nil {
+			return err
+		}
 		off := p1 - p0
-		return w.updateBody(p0, p1-off, s)
+		if err := w.updateBody(p0, p1-off, s); err != nil {
+			return err
+		}
+		w.receiveEcho(e)
+		if e.C1 == 'K' {
+			w.triggerSignature(p1, s)
+		}
+		return nil
 	case 'D':
 		w.setTag(true)
-		return w.updateBody(p0, p1, "")
+		if err := w.updateBody(p0, p1, ""); err != nil {
+			return err
+		}
+		w.receiveEcho(e)
+		return nil
 	case 'x', 'X':
 		return w.execute(e)
 	case 'l', 'L':
@@ -141,12 +196,38 @@ func (w *Win) handleEvent(e *acme.Event) error {
 	return nil
 }
 
+// eventText returns the text inserted by e.
+// Acme omits the text from the event if it is too long; then it is read from the body.
+func (w *Win) eventText(e *acme.Event) (string, error) {
+	if e.Nr == e.Q1-e.Q0 {
+		return string(e.Text), nil
+	}
+	if err := w.acme.Addr("#%d,#%d", e.Q0, e.Q1); err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	b := make([]byte, 8192)
+	for {
+		n, err := w.acme.Read("xdata", b)
+		if n > 0 {
+			buf.Write(b[:n])
+		}
+		if err == io.EOF || n == 0 {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+	return buf.String(), nil
+}
+
 func (w *Win) updateBody(p0, p1 outline.Pos, s string) error {
 	params, err := w.makeContentChangeEvent(p0, p1, s)
 	if err != nil {
 		return err
 	}
-	if err := w.c.DidChangeTextDocument(params); err != nil {
+	if err := w.srv.Client().DidChangeTextDocument(params); err != nil {
 		return err
 	}
 	return w.f.Update(p0, p1, s)
@@ -161,10 +242,11 @@ func (w *Win) makeContentChangeEvent(p0, p1 outline.Pos, s string) (*lsp.DidChan
 	if err != nil {
 		return nil, err
 	}
+	version := int(atomic.AddInt64(&w.version, 1))
 	return &lsp.DidChangeTextDocumentParams{
 		TextDocument: lsp.VersionedTextDocumentIdentifier{
 			TextDocumentIdentifier: w.DocumentID(),
-			Version:                nil, // TODO(lufia): implement
+			Version:                &version,
 		},
 		ContentChanges: []lsp.TextDocumentContentChangeEvent{
 			{
@@ -186,13 +268,46 @@ func (w *Win) makeContentChangeEvent(p0, p1 outline.Pos, s string) (*lsp.DidChan
 }
 
 func (w *Win) execute(e *acme.Event) error {
-	switch string(e.Text) {
+	cmd, arg := parseCommand(e)
+	switch cmd {
 	case "Put":
 		return w.ExecPut()
 	case "Ref":
 		return w.ExecRef()
+	case "Impl":
+		return w.ExecImpl()
+	case "Typedef":
+		return w.ExecTypedef()
+	case "Decl":
+		return w.ExecDecl()
 	case "Doc":
 		return w.ExecDoc()
+	case "Hover":
+		return w.ExecHover()
+	case "Complete":
+		return w.ExecComplete()
+	case "Sig":
+		return w.ExecSig()
+	case "Rename":
+		return w.ExecRename(arg)
+	case "Fmt":
+		return w.ExecFmt()
+	case "Action":
+		return w.ExecAction()
+	case "Symbols":
+		return w.ExecSymbols()
+	case "Sym":
+		return w.ExecSym(arg)
+	case "Callers":
+		return w.ExecCallers()
+	case "Callees":
+		return w.ExecCallees()
+	case "Types":
+		return w.ExecTypes()
+	case "Occur":
+		return w.ExecOccur()
+	case "Next":
+		return w.ExecNext()
 	case "Test":
 		return errors.New("not implement")
 	default:
@@ -201,20 +316,80 @@ func (w *Win) execute(e *acme.Event) error {
 	}
 }
 
+// parseCommand splits the command of e into its name and argument.
+// The argument is either a text that follows the name,
+// or a text chorded with 2-1 buttons.
+func parseCommand(e *acme.Event) (cmd, arg string) {
+	a := strings.Fields(string(e.Text))
+	if len(a) == 0 {
+		return "", ""
+	}
+	cmd = a[0]
+	arg = strings.Join(a[1:], " ")
+	if e.Flag&8 != 0 {
+		arg = strings.TrimSpace(string(e.Arg))
+	}
+	return cmd, arg
+}
+
+// newContext returns a context that is canceled after the duration of timeout flag.
+func newContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), *timeoutFlag)
+}
+
 // readCursor returns a beginning address pointed by cursor.
 func (w *Win) readCursor() (int, error) {
+	q0, _, err := w.readSelection()
+	return q0, err
+}
+
+// readSelection returns the addresses of the selected text.
+func (w *Win) readSelection() (q0, q1 int, err error) {
 	// Acme can't set addr to dot at only once
 	// from a window is opened if addr isn't reset by 0.
 	w.acme.Addr("0")
 
 	if err := w.acme.Ctl("addr=dot"); err != nil {
-		return 0, err
+		return 0, 0, err
 	}
-	q0, _, err := w.acme.ReadAddr()
+	return w.acme.ReadAddr()
+}
+
+// cursorParams returns a position pointed by cursor in the document.
+func (w *Win) cursorParams() (*lsp.TextDocumentPositionParams, error) {
+	q, err := w.readCursor()
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
-	return q0, nil
+	return w.positionParams(q)
+}
+
+// positionParams returns a position of offset q in the document.
+func (w *Win) positionParams(q int) (*lsp.TextDocumentPositionParams, error) {
+	addr, err := w.f.Addr(outline.Pos(q))
+	if err != nil {
+		return nil, err
+	}
+	return &lsp.TextDocumentPositionParams{
+		TextDocument: w.DocumentID(),
+		Position: lsp.Position{
+			Line:      int(addr.Line),
+			Character: int(addr.Col),
+		},
+	}, nil
+}
+
+// lspRange returns the range between offsets q0 and q1 in the document.
+func (w *Win) lspRange(q0, q1 int) (*lsp.Range, error) {
+	p0, err := w.positionParams(q0)
+	if err != nil {
+		return nil, err
+	}
+	p1, err := w.positionParams(q1)
+	if err != nil {
+		return nil, err
+	}
+	return &lsp.Range{Start: p0.Position, End: p1.Position}, nil
 }
 
 func (w *Win) look(e *acme.Event) error {
@@ -222,14 +397,16 @@ func (w *Win) look(e *acme.Event) error {
 	if err != nil {
 		return err
 	}
-	r := w.c.GotoDefinition(&lsp.TextDocumentPositionParams{
+	r := w.srv.Client().GotoDefinition(&lsp.TextDocumentPositionParams{
 		TextDocument: w.DocumentID(),
 		Position: lsp.Position{
 			Line:      int(addr.Line),
 			Character: int(addr.Col),
 		},
 	})
-	if err := r.Wait(); err != nil {
+	ctx, cancel := newContext()
+	defer cancel()
+	if err := r.WaitContext(ctx); err != nil || len(r.Locations) == 0 {
 		return w.acme.WriteEvent(e)
 	}
 
@@ -280,12 +457,122 @@ func (w *Win) readRange(f io.ReadSeeker, q0, q1 int) ([]byte, error) {
 	return buf, nil
 }
 
+// ExecPut saves the body of w.
+// If FormatOnPut is enabled for the language, ExecPut formats the body before saving.
+// Then the body is saved after acme reports all changes of the formatting,
+// so that the server receives them before the body is saved.
+// The body is saved even if the formatting failed.
 func (w *Win) ExecPut() error {
-	defer w.acme.Ctl("put")
-	return w.c.WillSave(&lsp.WillSaveTextDocumentParams{
+	err := w.srv.Client().WillSave(&lsp.WillSaveTextDocumentParams{
 		TextDocument: w.DocumentID(),
 		Reason:       lsp.TextDocumentSaveReasonManual,
 	})
+	if err == nil && w.lang.FormatOnPut && w.srv.Client().Capabilities().DocumentFormattingProvider {
+		if err = w.format(); err != nil {
+			err = fmt.Errorf("can't format %s: %w", w.file, err)
+		}
+	}
+	if len(w.echoes) > 0 {
+		w.putOnEcho = true
+	} else {
+		w.acme.Ctl("put")
+	}
+	return err
+}
+
+// formattingOptions returns the options for formatting w.
+func (w *Win) formattingOptions() lsp.FormattingOptions {
+	return lsp.FormattingOptions{
+		TabSize:      w.lang.TabSize,
+		InsertSpaces: w.lang.InsertSpaces,
+	}
+}
+
+// format formats the whole body of w.
+// Changes made by format are recorded in w.echoes.
+func (w *Win) format() error {
+	result := w.srv.Client().Formatting(&lsp.DocumentFormattingParams{
+		TextDocument: w.DocumentID(),
+		Options:      w.formattingOptions(),
+	})
+	ctx, cancel := newContext()
+	defer cancel()
+	if err := result.WaitContext(ctx); err != nil {
+		return err
+	}
+	a, err := makeTextEdits(w.f, result.TextEdits)
+	if err != nil {
+		return err
+	}
+	w.echoes = echoesOf(a)
+	if err := writeTextEdits(w.acme, a); err != nil {
+		// we can't know which changes are written.
+		w.echoes = nil
+		return err
+	}
+	return nil
+}
+
+// ExecFmt formats the selected text of w.
+// If nothing is selected, or the server can't format a part of the document,
+// ExecFmt formats the whole document instead.
+func (w *Win) ExecFmt() error {
+	q0, q1, err := w.readSelection()
+	if err != nil {
+		return err
+	}
+	capa := w.srv.Client().Capabilities()
+	switch {
+	case q0 < q1 && capa.DocumentRangeFormattingProvider:
+		return w.formatRange(q0, q1)
+	case capa.DocumentFormattingProvider:
+		return w.format()
+	default:
+		return errors.New("the server doesn't support formatting")
+	}
+}
+
+// formatRange formats the text between q0 and q1 of w.
+func (w *Win) formatRange(q0, q1 int) error {
+	r, err := w.lspRange(q0, q1)
+	if err != nil {
+		return err
+	}
+	result := w.srv.Client().RangeFormatting(&lsp.DocumentRangeFormattingParams{
+		TextDocument: w.DocumentID(),
+		Range:        *r,
+		Options:      w.formattingOptions(),
+	})
+	ctx, cancel := newContext()
+	defer cancel()
+	if err := result.WaitContext(ctx); err != nil {
+		return err
+	}
+	return applyEdits(w.acme, w.f, result.TextEdits)
+}
+
+// receiveEcho removes the change reported by e from w.echoes.
+// When all of them are reported, it saves the body if w.putOnEcho is set.
+func (w *Win) receiveEcho(e *acme.Event) {
+	if len(w.echoes) == 0 || (e.C1 != 'E' && e.C1 != 'F') {
+		return
+	}
+	x := &w.echoes[0]
+	switch {
+	case e.C2 == x.c2 && e.Q0 == x.q0 && e.Q1 == x.q1:
+		w.echoes = w.echoes[1:]
+	case e.C2 == 'I' && x.c2 == 'I' && e.Q0 == x.q0 && e.Q1 < x.q1:
+		// a long text is reported in several events.
+		x.q0 = e.Q1
+		return
+	default:
+		// the body is changed by someone else; don't wait for the rest.
+		w.echoes = nil
+	}
+	if len(w.echoes) == 0 && w.putOnEcho {
+		w.putOnEcho = false
+		w.acme.Ctl("put")
+	}
 }
 
 func (w *Win) ExecRef() error {
@@ -297,7 +584,7 @@ func (w *Win) ExecRef() error {
 	if err != nil {
 		return err
 	}
-	result := w.c.References(&lsp.ReferenceParams{
+	result := w.srv.Client().References(&lsp.ReferenceParams{
 		TextDocumentPositionParams: lsp.TextDocumentPositionParams{
 			TextDocument: w.DocumentID(),
 			Position: lsp.Position{
@@ -309,21 +596,71 @@ func (w *Win) ExecRef() error {
 			IncludeDeclaration: false,
 		},
 	})
-	if err := result.Wait(); err != nil {
+	ctx, cancel := newContext()
+	defer cancel()
+	if err := result.WaitContext(ctx); err != nil {
+		return err
+	}
+	w.printLocations(result.Locations)
+	return nil
+}
+
+// ExecImpl prints implementations of the interface or the method at the cursor.
+func (w *Win) ExecImpl() error {
+	params, err := w.cursorParams()
+	if err != nil {
 		return err
 	}
-	for _, loc := range result.Locations {
+	return w.showLocations(w.srv.Client().Implementation(params))
+}
+
+// ExecDecl prints the declaration of the symbol at the cursor.
+func (w *Win) ExecDecl() error {
+	params, err := w.cursorParams()
+	if err != nil {
+		return err
+	}
+	return w.showLocations(w.srv.Client().Declaration(params))
+}
+
+// ExecTypedef prints the definition of the type of the symbol at the cursor.
+func (w *Win) ExecTypedef() error {
+	params, err := w.cursorParams()
+	if err != nil {
+		return err
+	}
+	return w.showLocations(w.srv.Client().TypeDefinition(params))
+}
+
+// showLocations waits for result, then prints its locations.
+func (w *Win) showLocations(result *lsp.LocationsResult) error {
+	ctx, cancel := newContext()
+	defer cancel()
+	if err := result.WaitContext(ctx); err != nil {
+		return err
+	}
+	if len(result.Locations) == 0 {
+		return errors.New("no locations")
+	}
+	w.printLocations(result.Locations)
+	return nil
+}
+
+// printLocations prints addresses of locs to the errors window.
+func (w *Win) printLocations(locs []lsp.Location) {
+	for _, loc := range locs {
 		file := loc.URI.String()
 		w.acme.Errf("%s:%d", file, loc.Range.Start.Line+1)
 	}
-	return nil
 }
 
 func (w *Win) ExecDoc() error {
-	result := w.c.DocumentLink(&lsp.DocumentLinkParams{
+	result := w.srv.Client().DocumentLink(&lsp.DocumentLinkParams{
 		TextDocument: w.DocumentID(),
 	})
-	if err := result.Wait(); err != nil {
+	ctx, cancel := newContext()
+	defer cancel()
+	if err := result.WaitContext(ctx); err != nil {
 		return err
 	}
 	for _, link := range result.DocumentLinks {
@@ -334,41 +671,206 @@ func (w *Win) ExecDoc() error {
 	return nil
 }
 
-func rangeToPos(file string, r *lsp.Range) (q0, q1 int, err error) {
-	fin, err := os.Open(file)
+// ExecHover shows the documentation of the symbol pointed by cursor in +lsp window.
+func (w *Win) ExecHover() error {
+	params, err := w.cursorParams()
 	if err != nil {
-		return
+		return err
 	}
-	defer fin.Close()
+	result := w.srv.Client().Hover(params)
+	ctx, cancel := newContext()
+	defer cancel()
+	if err := result.WaitContext(ctx); err != nil {
+		return err
+	}
+	if result.Hover == nil {
+		return errors.New("no information")
+	}
+	_, err = writeWin(outputWinName(w.file, "+lsp"), result.Hover.Contents.Value)
+	return err
+}
 
-	f, err := outline.NewFile(fin)
+// ExecSig shows the signature of the function call at the cursor in +lsp window.
+func (w *Win) ExecSig() error {
+	params, err := w.cursorParams()
+	if err != nil {
+		return err
+	}
+	h, err := w.signatureHelp(params, &lsp.SignatureHelpContext{
+		TriggerKind: lsp.SignatureHelpTriggerKindInvoked,
+	})
+	if err != nil {
+		return err
+	}
+	if h == nil {
+		return errors.New("no signature")
+	}
+	return w.showSignature(h, true)
+}
+
+// triggerSignature shows the signature if s typed at q ends with a trigger character.
+// The request runs in background; its result is shown only if the cursor stays at q.
+// w.mu must be held.
+func (w *Win) triggerSignature(q outline.Pos, s string) {
+	opts := w.srv.Client().Capabilities().SignatureHelpProvider
+	if s == "" {
+		return
+	}
+	r := []rune(s)
+	c := string(r[len(r)-1])
+	sc := &lsp.SignatureHelpContext{
+		TriggerKind:      lsp.SignatureHelpTriggerKindTriggerCharacter,
+		TriggerCharacter: c,
+	}
+	switch {
+	case contains(opts.TriggerCharacters, c):
+	case contains(opts.RetriggerCharacters, c):
+		sc.IsRetrigger = true
+	default:
+		return
+	}
+	params, err := w.positionParams(int(q))
 	if err != nil {
 		return
 	}
-	pos := func(p lsp.Position) (int, error) {
-		v, err := f.Pos(outline.Addr{
-			Line: uint(p.Line),
-			Col:  outline.Pos(p.Character),
-		})
+	version := w.Version()
+	go func() {
+		h, err := w.signatureHelp(params, sc)
 		if err != nil {
-			return 0, err
+			fmt.Fprintf(serverLog, "lsp: signature help: %v\n", err)
+			return
+		}
+		// this is not requested explicitly; the server might not have a signature.
+		if h == nil || !w.cursorStays(int(q), version) {
+			return
+		}
+		if err := w.showSignature(h, false); err != nil {
+			fmt.Fprintf(serverLog, "lsp: signature help: %v\n", err)
+		}
+	}()
+}
+
+// cursorStays reports whether the cursor of w is still at q,
+// and the body of w is not changed since version.
+func (w *Win) cursorStays(q, version int) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.Version() != version {
+		return false
+	}
+	p, err := w.readCursor()
+	return err == nil && p == q
+}
+
+func contains(a []string, s string) bool {
+	for _, v := range a {
+		if v == s {
+			return true
 		}
-		return int(v), nil
 	}
-	q0, err = pos(r.Start)
+	return false
+}
+
+// signatureHelp requests the signature at params.
+// It returns nil if the server has no signatures.
+func (w *Win) signatureHelp(params *lsp.TextDocumentPositionParams, sc *lsp.SignatureHelpContext) (*lsp.SignatureHelp, error) {
+	result := w.srv.Client().SignatureHelp(&lsp.SignatureHelpParams{
+		TextDocumentPositionParams: *params,
+		Context:                    sc,
+	})
+	ctx, cancel := newContext()
+	defer cancel()
+	if err := result.WaitContext(ctx); err != nil {
+		return nil, err
+	}
+	if result.Help == nil || len(result.Help.Signatures) == 0 {
+		return nil, nil
+	}
+	return result.Help, nil
+}
+
+// showSignature shows the active signature of h in +lsp window
+// and selects the active parameter in it.
+// If show is false, the window is updated without being raised.
+func (w *Win) showSignature(h *lsp.SignatureHelp, show bool) error {
+	i := h.ActiveSignature
+	if i < 0 || i >= len(h.Signatures) {
+		i = 0
+	}
+	sig := h.Signatures[i]
+	text := sig.Label + "\n"
+	if sig.Documentation != nil {
+		text += "\n" + sig.Documentation.Value + "\n"
+	}
+	win, err := updateWin(outputWinName(w.file, "+lsp"), text)
 	if err != nil {
-		return
+		return err
+	}
+	if show {
+		win.Ctl("show")
+	}
+	n := h.ActiveParameter
+	if sig.ActiveParameter != nil {
+		n = *sig.ActiveParameter
+	}
+	if n < 0 || n >= len(sig.Parameters) {
+		return nil
+	}
+	if q0, q1, ok := sig.Parameters[n].Label.Range(sig.Label); ok {
+		win.Addr("#%d,#%d", q0, q1)
+		win.Ctl("dot=addr")
+		if show {
+			win.Ctl("show")
+		}
+	}
+	return nil
+}
+
+// ExecRename renames the symbol at the cursor to name in the workspace.
+func (w *Win) ExecRename(name string) error {
+	if name == "" {
+		return errors.New("usage: Rename newname")
 	}
-	q1, err = pos(r.End)
+	params, err := w.cursorParams()
+	if err != nil {
+		return err
+	}
+	c := w.srv.Client()
+	ctx, cancel := newContext()
+	defer cancel()
+	if c.Capabilities().RenameProvider.PrepareProvider {
+		r := c.PrepareRename(params)
+		if err := r.WaitContext(ctx); err != nil {
+			return err
+		}
+		if r.Range == nil {
+			return errors.New("can't rename the symbol at the cursor")
+		}
+	}
+	result := c.Rename(&lsp.RenameParams{
+		TextDocumentPositionParams: *params,
+		NewName:                    name,
+	})
+	if err := result.WaitContext(ctx); err != nil {
+		return err
+	}
+	if result.Edit == nil {
+		return errors.New("no changes")
+	}
+	return applyWorkspaceEdit(result.Edit)
+}
+
+func rangeToPos(file string, r *lsp.Range) (q0, q1 int, err error) {
+	f, err := outline.Open(file)
 	if err != nil {
 		return
 	}
-	return
+	return rangeOf(f, r)
 }
 
 func (w *Win) Close() {
 	w.acme.CloseFiles()
-	err := w.c.DidCloseTextDocument(&lsp.DidCloseTextDocumentParams{
+	err := w.srv.Client().DidCloseTextDocument(&lsp.DidCloseTextDocumentParams{
 		TextDocument: w.DocumentID(),
 	})
 	if err != nil {
@@ -376,75 +878,148 @@ func (w *Win) Close() {
 	}
 }
 
-func start(c *lsp.Client) error {
+// watchEvents handles notifications from the server until the connection is lost.
+func watchEvents(c *lsp.Client) {
+	c.OnPublishDiagnostics(func(params *lsp.PublishDiagnosticsParams) {
+		setDiagnostics(params.URI, params.Diagnostics)
+		if !*debugFlag {
+			return
+		}
+		file := params.URI.String()
+		for _, v := range params.Diagnostics {
+			q0, q1, err := rangeToPos(file, &v.Range)
+			if err != nil {
+				acme.Errf(file, "lsp: %s:%d: %s", file, v.Range.Start.Line+1, v.Message)
+				continue
+			}
+			acme.Errf(file, "%s:#%d,#%d %s", path.Base(file), q0, q1, v.Message)
+		}
+	})
+	c.OnShowMessage(func(params *lsp.ShowMessageParams) {
+		acme.Errf(".", "lsp: %s", params.Message)
+	})
+	c.OnLogMessage(func(params *lsp.LogMessageParams) {
+		fmt.Fprintf(serverLog, "%s\n", params.Message)
+	})
 	go func() {
 		for msg := range c.Event {
-			switch msg.Method {
-			case "textDocument/publishDiagnostics":
-				if !*debugFlag {
-					continue
-				}
-				var params lsp.PublishDiagnosticsParams
-				err := json.Unmarshal([]byte(msg.Params), &params)
-				if err != nil {
-					acme.Errf(".", "lsp: %s: %s", msg.Method, msg.Params)
-					continue
-				}
-				file := params.URI.String()
-				for _, v := range params.Diagnostics {
-					q0, q1, err := rangeToPos(file, &v.Range)
-					if err != nil {
-						acme.Errf(file, "lsp: %s: %s", msg.Method, msg.Params)
-						continue
-					}
-					acme.Errf(file, "%s:#%d,#%d %s", path.Base(file), q0, q1, v.Message)
-				}
-			default:
-				acme.Errf(".", "lsp: %s: %s", msg.Method, msg.Params)
-			}
+			acme.Errf(".", "lsp: %s: %s", msg.Method, msg.Params)
 		}
 	}()
+}
 
-	r, err := acme.Log()
-	if err != nil {
-		return err
+var (
+	winsMu sync.Mutex
+	wins   = make(map[int]*Win) // watched windows by ID
+)
+
+// lookupFile returns the watched window that edits file.
+func lookupFile(file string) (*Win, bool) {
+	winsMu.Lock()
+	defer winsMu.Unlock()
+	for _, w := range wins {
+		if w.file == file {
+			return w, true
+		}
 	}
-	defer r.Close()
+	return nil, false
+}
+
+func start(srv *Server) error {
+	logc := make(chan acme.LogEvent)
+	errc := make(chan error, 1)
+	go readLog(logc, errc)
 
-	wins := make(map[int]*Win)
 	for {
-		ev, err := r.Read()
-		if err != nil {
+		var ev acme.LogEvent
+		select {
+		case ev = <-logc:
+		case err := <-errc:
 			return err
+		case <-srv.Client().Done():
+			err := srv.Client().Err()
+			fmt.Fprintf(serverLog, "*** connection is lost: %v ***\n", err)
+			acme.Errf(".", "lsp: connection is lost: %v; restarting", err)
+			if err := srv.Restart(); err != nil {
+				fmt.Fprintf(serverLog, "*** %v ***\n", err)
+				return err
+			}
+			// a window that is busy must not block others.
+			winsMu.Lock()
+			for _, w := range wins {
+				go func(w *Win) {
+					if err := w.Reopen(); err != nil {
+						acme.Errf(w.file, "lsp: can't reopen %s: %v", w.file, err)
+					}
+				}(w)
+			}
+			winsMu.Unlock()
+			continue
 		}
 		// TODO(lufia): when open a directory that exists go.mod and outside of GOPATH,
 		// we shoudl register that directory as LSP workspace.
-		if path.Ext(ev.Name) != ".go" {
+		lang := languageOf(ev.Name)
+		if lang == nil {
 			continue
 		}
 		switch ev.Op {
 		case "new":
-			w, err := OpenFile(ev.ID, ev.Name, c)
+			w, err := OpenFile(ev.ID, ev.Name, lang, srv)
 			if err != nil {
 				acme.Errf("./log", "can't watch: %v", err)
 				continue
 			}
+			winsMu.Lock()
 			wins[ev.ID] = w
+			winsMu.Unlock()
 			go w.watch()
 		case "get":
-			if w, ok := wins[ev.ID]; ok {
+			if w, ok := lookupWinID(ev.ID); ok {
 				w.Reload()
 			}
 		case "put":
-			if w, ok := wins[ev.ID]; ok {
+			if w, ok := lookupWinID(ev.ID); ok {
 				w.setTag(false)
 				w.didSave()
+				go func() {
+					if err := w.refreshSymbols(); err != nil {
+						acme.Errf(w.file, "lsp: can't refresh symbols: %v", err)
+					}
+				}()
 			}
 		case "del":
-			if w, ok := wins[ev.ID]; ok {
+			winsMu.Lock()
+			w, ok := wins[ev.ID]
+			delete(wins, ev.ID)
+			winsMu.Unlock()
+			if ok {
 				w.Close()
 			}
-			delete(wins, ev.ID)
 		}
 	}
 }
+
+func lookupWinID(id int) (*Win, bool) {
+	winsMu.Lock()
+	defer winsMu.Unlock()
+	w, ok := wins[id]
+	return w, ok
+}
+
+// readLog sends events of acme's log file to logc.
+func readLog(logc chan<- acme.LogEvent, errc chan<- error) {
+	r, err := acme.Log()
+	if err != nil {
+		errc <- err
+		return
+	}
+	defer r.Close()
+	for {
+		ev, err := r.Read()
+		if err != nil {
+			errc <- err
+			return
+		}
+		logc <- ev
+	}
+}
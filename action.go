@@ -0,0 +1,102 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/lufia/acme-lsp/lsp"
+)
+
+// ExecAction shows code actions for the selected text and diagnostics on it.
+func (w *Win) ExecAction() error {
+	q0, q1, err := w.readSelection()
+	if err != nil {
+		return err
+	}
+	r, err := w.lspRange(q0, q1)
+	if err != nil {
+		return err
+	}
+	id := w.DocumentID()
+	result := w.srv.Client().CodeAction(&lsp.CodeActionParams{
+		TextDocument: id,
+		Range:        *r,
+		Context: lsp.CodeActionContext{
+			Diagnostics: diagnosticsIn(id.URI, *r),
+		},
+	})
+	ctx, cancel := newContext()
+	defer cancel()
+	if err := result.WaitContext(ctx); err != nil {
+		return err
+	}
+	if len(result.Actions) == 0 {
+		return errors.New("no actions")
+	}
+	return showActions(w, result.Actions)
+}
+
+// showActions lists actions in a window named +action.
+// When an action is clicked by 3 button, it is executed.
+func showActions(w *Win, actions []lsp.CodeAction) error {
+	l, err := openListWin(outputWinName(w.file, "+action"), "")
+	if err != nil {
+		return err
+	}
+	lines := make([]string, len(actions))
+	for i, a := range actions {
+		s := a.Title
+		if a.Kind != "" {
+			s += fmt.Sprintf("\t[%s]", a.Kind)
+		}
+		if a.Disabled != nil {
+			s += fmt.Sprintf("\t(disabled: %s)", a.Disabled.Reason)
+		}
+		lines[i] = s
+	}
+	return l.show(lines, 0, func(i int) error {
+		a := &actions[i]
+		if a.Disabled != nil {
+			return fmt.Errorf("%s: %s", a.Title, a.Disabled.Reason)
+		}
+		if err := runAction(w, resolveAction(w, a)); err != nil {
+			return err
+		}
+		return l.win.Del(true)
+	}, nil)
+}
+
+// resolveAction returns a that is filled with its edit if the server supports it.
+func resolveAction(w *Win, a *lsp.CodeAction) *lsp.CodeAction {
+	client := w.srv.Client()
+	if a.Edit != nil || !client.Capabilities().CodeActionProvider.ResolveProvider {
+		return a
+	}
+	result := client.ResolveCodeAction(a)
+	ctx, cancel := newContext()
+	defer cancel()
+	if err := result.WaitContext(ctx); err != nil {
+		return a
+	}
+	return &result.Action
+}
+
+// runAction applies the edit of a, then executes the command of a.
+func runAction(w *Win, a *lsp.CodeAction) error {
+	if a.Edit != nil {
+		if err := applyWorkspaceEdit(a.Edit); err != nil {
+			return err
+		}
+	}
+	if a.Command == nil {
+		return nil
+	}
+	// the server might request workspace/applyEdit while executing the command.
+	result := w.srv.Client().ExecuteCommand(&lsp.ExecuteCommandParams{
+		Command:   a.Command.Command,
+		Arguments: a.Command.Arguments,
+	})
+	ctx, cancel := newContext()
+	defer cancel()
+	return result.WaitContext(ctx)
+}
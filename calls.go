@@ -0,0 +1,163 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/lufia/acme-lsp/lsp"
+)
+
+// callTree shows the call hierarchy in a window named +calls.
+// Executing Callers or Callees in the window expands the entry at dot.
+type callTree struct {
+	list  *listWin
+	srv   *Server
+	nodes []*callNode // nodes[i] is shown at line i
+}
+
+// callNode is an entry of callTree.
+type callNode struct {
+	item  lsp.CallHierarchyItem
+	depth int
+	mark  string // "<-" for callers, "->" for callees
+	file  string
+	line  int // 0-origin
+}
+
+// ExecCallers shows functions that call the function at the cursor.
+func (w *Win) ExecCallers() error {
+	return w.showCalls(true)
+}
+
+// ExecCallees shows functions that are called by the function at the cursor.
+func (w *Win) ExecCallees() error {
+	return w.showCalls(false)
+}
+
+func (w *Win) showCalls(incoming bool) error {
+	params, err := w.cursorParams()
+	if err != nil {
+		return err
+	}
+	result := w.srv.Client().PrepareCallHierarchy(params)
+	ctx, cancel := newContext()
+	defer cancel()
+	if err := result.WaitContext(ctx); err != nil {
+		return err
+	}
+	if len(result.Items) == 0 {
+		return errors.New("no functions at the cursor")
+	}
+	l, err := openListWin(outputWinName(w.file, "+calls"), "Callers Callees")
+	if err != nil {
+		return err
+	}
+	t := &callTree{list: l, srv: w.srv}
+	for _, item := range result.Items {
+		t.nodes = append(t.nodes, &callNode{
+			item: item,
+			file: item.URI.String(),
+			line: item.SelectionRange.Start.Line,
+		})
+	}
+	// expand from the last one to keep indices of others
+	for i := len(t.nodes) - 1; i >= 0; i-- {
+		if err := t.expand(i, incoming); err != nil {
+			return err
+		}
+	}
+	return t.redraw(0)
+}
+
+// expandAt expands i-th entry one level deeper.
+func (t *callTree) expandAt(i int, incoming bool) error {
+	if err := t.expand(i, incoming); err != nil {
+		return err
+	}
+	return t.redraw(i)
+}
+
+// expand replaces children of t.nodes[i] with its callers or callees.
+func (t *callTree) expand(i int, incoming bool) error {
+	node := t.nodes[i]
+	children, err := t.calls(node, incoming)
+	if err != nil {
+		return err
+	}
+	j := i + 1
+	for j < len(t.nodes) && t.nodes[j].depth > node.depth {
+		j++
+	}
+	a := make([]*callNode, 0, len(t.nodes)-(j-i-1)+len(children))
+	a = append(a, t.nodes[:i+1]...)
+	a = append(a, children...)
+	a = append(a, t.nodes[j:]...)
+	t.nodes = a
+	return nil
+}
+
+// calls returns callers or callees of node as its children.
+// A caller is placed at the call site, and a callee is placed at its definition.
+func (t *callTree) calls(node *callNode, incoming bool) ([]*callNode, error) {
+	client := t.srv.Client()
+	ctx, cancel := newContext()
+	defer cancel()
+	var a []*callNode
+	if incoming {
+		result := client.IncomingCalls(&lsp.CallHierarchyIncomingCallsParams{
+			Item: node.item,
+		})
+		if err := result.WaitContext(ctx); err != nil {
+			return nil, err
+		}
+		for _, c := range result.Calls {
+			line := c.From.SelectionRange.Start.Line
+			if len(c.FromRanges) > 0 {
+				line = c.FromRanges[0].Start.Line
+			}
+			a = append(a, &callNode{
+				item:  c.From,
+				depth: node.depth + 1,
+				mark:  "<-",
+				file:  c.From.URI.String(),
+				line:  line,
+			})
+		}
+		return a, nil
+	}
+	result := client.OutgoingCalls(&lsp.CallHierarchyOutgoingCallsParams{
+		Item: node.item,
+	})
+	if err := result.WaitContext(ctx); err != nil {
+		return nil, err
+	}
+	for _, c := range result.Calls {
+		a = append(a, &callNode{
+			item:  c.To,
+			depth: node.depth + 1,
+			mark:  "->",
+			file:  c.To.URI.String(),
+			line:  c.To.SelectionRange.Start.Line,
+		})
+	}
+	return a, nil
+}
+
+// redraw writes all entries into the window, then selects i-th entry.
+func (t *callTree) redraw(i int) error {
+	dir := path.Dir(t.list.name)
+	lines := make([]string, len(t.nodes))
+	for k, node := range t.nodes {
+		s := strings.Repeat("\t", node.depth)
+		if node.mark != "" {
+			s += node.mark + " "
+		}
+		lines[k] = s + fmt.Sprintf("%s\t%s:%d", node.item.Name, relPath(dir, node.file), node.line+1)
+	}
+	return t.list.show(lines, i, nil, map[string]func(int) error{
+		"Callers": func(i int) error { return t.expandAt(i, true) },
+		"Callees": func(i int) error { return t.expandAt(i, false) },
+	})
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"unicode"
+
+	"github.com/lufia/acme-lsp/lsp"
+)
+
+// ExecComplete shows candidates of the completion at the cursor.
+func (w *Win) ExecComplete() error {
+	q, err := w.readCursor()
+	if err != nil {
+		return err
+	}
+	params, err := w.positionParams(q)
+	if err != nil {
+		return err
+	}
+	result := w.srv.Client().Completion(&lsp.CompletionParams{
+		TextDocumentPositionParams: *params,
+		Context: &lsp.CompletionContext{
+			TriggerKind: lsp.CompletionTriggerKindInvoked,
+		},
+	})
+	ctx, cancel := newContext()
+	defer cancel()
+	if err := result.WaitContext(ctx); err != nil {
+		return err
+	}
+	items := plainItems(result.List.Items)
+	if len(items) == 0 {
+		return errors.New("no candidates")
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		return sortText(&items[i]) < sortText(&items[j])
+	})
+	return showCompletion(w, q, items)
+}
+
+// plainItems returns items except snippets; the client can't expand them.
+func plainItems(items []lsp.CompletionItem) []lsp.CompletionItem {
+	a := items[:0]
+	for _, item := range items {
+		if item.InsertTextFormat != lsp.InsertTextFormatSnippet {
+			a = append(a, item)
+		}
+	}
+	return a
+}
+
+func sortText(item *lsp.CompletionItem) string {
+	if item.SortText != "" {
+		return item.SortText
+	}
+	return item.Label
+}
+
+// showCompletion lists items in a window named +complete.
+// When a candidate is clicked by 3 button, it is applied to w at the offset q.
+func showCompletion(w *Win, q int, items []lsp.CompletionItem) error {
+	l, err := openListWin(outputWinName(w.file, "+complete"), "")
+	if err != nil {
+		return err
+	}
+	version := w.Version()
+	lines := make([]string, len(items))
+	for i, item := range items {
+		lines[i] = fmt.Sprintf("%s\t%s", item.Label, item.Detail)
+	}
+	return l.show(lines, 0, func(i int) error {
+		item := resolveCompletion(w, &items[i])
+		if err := w.complete(q, version, item); err != nil {
+			return err
+		}
+		return l.win.Del(true)
+	}, nil)
+}
+
+// resolveCompletion returns item that is filled with details if the server supports it.
+func resolveCompletion(w *Win, item *lsp.CompletionItem) *lsp.CompletionItem {
+	client := w.srv.Client()
+	if !client.Capabilities().CompletionProvider.ResolveProvider {
+		return item
+	}
+	result := client.ResolveCompletionItem(item)
+	ctx, cancel := newContext()
+	defer cancel()
+	if err := result.WaitContext(ctx); err != nil {
+		return item
+	}
+	return &result.Item
+}
+
+// complete applies item that is requested at the offset q to the body of w.
+// If w is changed after the request, positions in item are no longer valid;
+// then complete fails.
+func (w *Win) complete(q, version int, item *lsp.CompletionItem) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.Version() != version {
+		return errors.New("the window is changed after the completion; run Complete again")
+	}
+
+	var edits []lsp.TextEdit
+	if item.TextEdit != nil {
+		edits = append(edits, *item.TextEdit)
+	} else {
+		e, err := w.insertWord(q, item)
+		if err != nil {
+			return err
+		}
+		edits = append(edits, *e)
+	}
+	edits = append(edits, item.AdditionalTextEdits...)
+	return applyEdits(w.acme, w.f, edits)
+}
+
+// insertWord returns an edit that replaces the word before q with item.
+func (w *Win) insertWord(q int, item *lsp.CompletionItem) (*lsp.TextEdit, error) {
+	body, err := w.acme.ReadAll("body")
+	if err != nil {
+		return nil, err
+	}
+	text := []rune(string(body))
+	if q > len(text) {
+		q = len(text)
+	}
+	q0 := q
+	for q0 > 0 && isIdent(text[q0-1]) {
+		q0--
+	}
+	p0, err := w.positionParams(q0)
+	if err != nil {
+		return nil, err
+	}
+	p1, err := w.positionParams(q)
+	if err != nil {
+		return nil, err
+	}
+	s := item.InsertText
+	if s == "" {
+		s = item.Label
+	}
+	return &lsp.TextEdit{
+		Range: lsp.Range{
+			Start: p0.Position,
+			End:   p1.Position,
+		},
+		NewText: s,
+	}, nil
+}
+
+func isIdent(c rune) bool {
+	return c == '_' || unicode.IsLetter(c) || unicode.IsDigit(c)
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"sync"
+
+	"github.com/lufia/acme-lsp/lsp"
+)
+
+var (
+	diagnosticsMu sync.Mutex
+	diagnostics   = make(map[lsp.DocumentURI][]lsp.Diagnostic)
+)
+
+// setDiagnostics replaces diagnostics of the document uri with a.
+func setDiagnostics(uri lsp.DocumentURI, a []lsp.Diagnostic) {
+	diagnosticsMu.Lock()
+	defer diagnosticsMu.Unlock()
+	if len(a) == 0 {
+		delete(diagnostics, uri)
+		return
+	}
+	diagnostics[uri] = a
+}
+
+// diagnosticsIn returns diagnostics of the document uri that overlap r.
+func diagnosticsIn(uri lsp.DocumentURI, r lsp.Range) []lsp.Diagnostic {
+	diagnosticsMu.Lock()
+	defer diagnosticsMu.Unlock()
+	a := []lsp.Diagnostic{}
+	for _, d := range diagnostics[uri] {
+		if !posLess(r.End, d.Range.Start) && !posLess(d.Range.End, r.Start) {
+			a = append(a, d)
+		}
+	}
+	return a
+}
+
+// posLess reports whether p is before q.
+func posLess(p, q lsp.Position) bool {
+	if p.Line != q.Line {
+		return p.Line < q.Line
+	}
+	return p.Character < q.Character
+}
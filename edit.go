@@ -0,0 +1,250 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+	"unicode/utf8"
+
+	"9fans.net/go/acme"
+	"github.com/lufia/acme-lsp/lsp"
+	"github.com/lufia/acme-lsp/outline"
+)
+
+// rangeOf returns offsets of r in f.
+func rangeOf(f *outline.File, r *lsp.Range) (q0, q1 int, err error) {
+	pos := func(p lsp.Position) (int, error) {
+		v, err := f.Pos(outline.Addr{
+			Line: uint(p.Line),
+			Col:  outline.Pos(p.Character),
+		})
+		if err != nil {
+			return 0, err
+		}
+		return int(v), nil
+	}
+	q0, err = pos(r.Start)
+	if err != nil {
+		return
+	}
+	q1, err = pos(r.End)
+	if err != nil {
+		return
+	}
+	return
+}
+
+// textEdit is a TextEdit that is converted to offsets.
+type textEdit struct {
+	q0, q1 int
+	text   string
+}
+
+// makeTextEdits converts edits to offsets in f,
+// then sorts them in reverse order to apply them from the end of the file.
+//
+// Edits at the same offset are applied in reverse order of edits
+// so that inserts at the same position appear in the order of edits,
+// except that a deletion is applied before an insertion at its beginning.
+func makeTextEdits(f *outline.File, edits []lsp.TextEdit) ([]textEdit, error) {
+	a := make([]textEdit, len(edits))
+	for i, e := range edits {
+		q0, q1, err := rangeOf(f, &e.Range)
+		if err != nil {
+			return nil, err
+		}
+		a[i] = textEdit{q0: q0, q1: q1, text: e.NewText}
+	}
+	index := make([]int, len(a))
+	for i := range index {
+		index[i] = i
+	}
+	sort.Slice(index, func(i, j int) bool {
+		x, y := &a[index[i]], &a[index[j]]
+		if x.q0 != y.q0 {
+			return x.q0 > y.q0
+		}
+		if x.q1 != y.q1 {
+			return x.q1 > y.q1
+		}
+		return index[i] > index[j]
+	})
+	sorted := make([]textEdit, len(a))
+	for i, k := range index {
+		sorted[i] = a[k]
+	}
+	return sorted, nil
+}
+
+// echo is a change that is made through the data file; acme reports it as an event.
+type echo struct {
+	c2     rune // 'I' or 'D'
+	q0, q1 int
+}
+
+// echoesOf returns changes that acme will report when a is written.
+func echoesOf(a []textEdit) []echo {
+	var echoes []echo
+	for _, e := range a {
+		if e.q0 < e.q1 {
+			echoes = append(echoes, echo{c2: 'D', q0: e.q0, q1: e.q1})
+		}
+		if n := utf8.RuneCountInString(e.text); n > 0 {
+			echoes = append(echoes, echo{c2: 'I', q0: e.q0, q1: e.q0 + n})
+		}
+	}
+	return echoes
+}
+
+// applyTextEdits applies edits that are sorted by makeTextEdits to s.
+func applyTextEdits(s []rune, edits []textEdit) []rune {
+	for _, e := range edits {
+		t := append([]rune(e.text), s[e.q1:]...)
+		s = append(s[:e.q0], t...)
+	}
+	return s
+}
+
+// applyEdits applies edits to the body of win.
+// f must represent the current body of win.
+//
+// Changes are written through the data file of win, so acme reports them
+// as events to the watcher of win; it will update its outline and the server.
+func applyEdits(win *acme.Win, f *outline.File, edits []lsp.TextEdit) error {
+	a, err := makeTextEdits(f, edits)
+	if err != nil {
+		return err
+	}
+	return writeTextEdits(win, a)
+}
+
+// eventTextSize is the maximum number of runes that acme reports with an event.
+const eventTextSize = 256
+
+// writeTextEdits writes edits that are sorted by makeTextEdits into the body of win.
+// A long text is split into pieces so that each event acme reports contains its text.
+func writeTextEdits(win *acme.Win, edits []textEdit) error {
+	for _, e := range edits {
+		if err := win.Addr("#%d,#%d", e.q0, e.q1); err != nil {
+			return err
+		}
+		// writing data sets addr to the end of the written text.
+		s := []rune(e.text)
+		for {
+			n := len(s)
+			if n > eventTextSize {
+				n = eventTextSize
+			}
+			if _, err := win.Write("data", []byte(string(s[:n]))); err != nil {
+				return err
+			}
+			s = s[n:]
+			if len(s) == 0 {
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// applyWorkspaceEdit applies all changes in e.
+// Documents opened in acme are edited through their windows,
+// others are rewritten on the disk.
+//
+// If a document in e is versioned, it must be the same version
+// as the document opened in acme; otherwise no changes are applied.
+func applyWorkspaceEdit(e *lsp.WorkspaceEdit) error {
+	if err := checkVersions(e.DocumentChanges, fileVersion); err != nil {
+		return err
+	}
+	list, err := acme.Windows()
+	if err != nil {
+		return err
+	}
+	ids := make(map[string]int)
+	for _, info := range list {
+		ids[info.Name] = info.ID
+	}
+	for uri, edits := range e.Edits() {
+		file := uri.String()
+		if id, ok := ids[file]; ok {
+			err = applyWinEdits(id, edits)
+		} else {
+			err = applyFileEdits(file, edits)
+		}
+		if err != nil {
+			return fmt.Errorf("can't edit %s: %w", file, err)
+		}
+	}
+	return nil
+}
+
+// fileVersion returns the version of file if it is opened in acme.
+func fileVersion(file string) (int, bool) {
+	w, ok := lookupFile(file)
+	if !ok {
+		return 0, false
+	}
+	return w.Version(), true
+}
+
+// checkVersions reports an error if a document in changes is versioned
+// but its version differs from the version that versionOf returns.
+// Documents that versionOf don't know are not checked.
+func checkVersions(changes []lsp.TextDocumentEdit, versionOf func(file string) (int, bool)) error {
+	for _, c := range changes {
+		if c.TextDocument.Version == nil {
+			continue
+		}
+		file := c.TextDocument.URI.String()
+		v, ok := versionOf(file)
+		if !ok {
+			continue
+		}
+		if want := *c.TextDocument.Version; v != want {
+			return fmt.Errorf("%s is version %d; the edit is for version %d", file, v, want)
+		}
+	}
+	return nil
+}
+
+// applyWinEdits applies edits to the body of the window id.
+func applyWinEdits(id int, edits []lsp.TextEdit) error {
+	win, err := acme.Open(id, nil)
+	if err != nil {
+		return err
+	}
+	defer win.CloseFiles()
+	body, err := win.ReadAll("body")
+	if err != nil {
+		return err
+	}
+	f, err := outline.NewFile(bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	return applyEdits(win, f, edits)
+}
+
+// applyFileEdits applies edits to file on the disk.
+func applyFileEdits(file string, edits []lsp.TextEdit) error {
+	body, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	f, err := outline.NewFile(bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	a, err := makeTextEdits(f, edits)
+	if err != nil {
+		return err
+	}
+	s := applyTextEdits([]rune(string(body)), a)
+	fi, err := os.Stat(file)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(file, []byte(string(s)), fi.Mode())
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/lufia/acme-lsp/lsp"
+	"github.com/lufia/acme-lsp/outline"
+)
+
+// edit returns a TextEdit that replaces from (l0, c0) to (l1, c1) with text.
+func edit(l0, c0, l1, c1 int, text string) lsp.TextEdit {
+	return lsp.TextEdit{
+		Range: lsp.Range{
+			Start: lsp.Position{Line: l0, Character: c0},
+			End:   lsp.Position{Line: l1, Character: c1},
+		},
+		NewText: text,
+	}
+}
+
+func TestMakeTextEdits(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		edits []lsp.TextEdit
+		want  string
+	}{
+		{
+			name:  "replace",
+			text:  "hello world\n",
+			edits: []lsp.TextEdit{edit(0, 6, 0, 11, "acme")},
+			want:  "hello acme\n",
+		},
+		{
+			name: "multiple lines",
+			text: "a\nb\nc\n",
+			edits: []lsp.TextEdit{
+				edit(0, 0, 0, 1, "x"),
+				edit(2, 0, 2, 1, "z"),
+			},
+			want: "x\nb\nz\n",
+		},
+		{
+			name: "inserts at same position",
+			text: "ad\n",
+			edits: []lsp.TextEdit{
+				edit(0, 1, 0, 1, "b"),
+				edit(0, 1, 0, 1, "c"),
+			},
+			want: "abcd\n",
+		},
+		{
+			name: "insert before delete",
+			text: "0123456789\n",
+			edits: []lsp.TextEdit{
+				edit(0, 5, 0, 5, "x"),
+				edit(0, 5, 0, 8, ""),
+			},
+			want: "01234x89\n",
+		},
+		{
+			name: "delete before insert",
+			text: "0123456789\n",
+			edits: []lsp.TextEdit{
+				edit(0, 5, 0, 8, ""),
+				edit(0, 5, 0, 5, "x"),
+			},
+			want: "01234x89\n",
+		},
+		{
+			name: "multibyte",
+			text: "あいう\nえお\n",
+			edits: []lsp.TextEdit{
+				edit(0, 1, 0, 2, "イ"),
+				edit(1, 2, 1, 2, "か"),
+			},
+			want: "あイう\nえおか\n",
+		},
+	}
+	for _, tt := range tests {
+		f, err := outline.NewFile(strings.NewReader(tt.text))
+		if err != nil {
+			t.Fatal(err)
+		}
+		a, err := makeTextEdits(f, tt.edits)
+		if err != nil {
+			t.Errorf("%s: makeTextEdits: %v", tt.name, err)
+			continue
+		}
+		s := string(applyTextEdits([]rune(tt.text), a))
+		if s != tt.want {
+			t.Errorf("%s: result = %q; want %q", tt.name, s, tt.want)
+		}
+	}
+}
+
+func TestApplyFileEdits(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		edits []lsp.TextEdit
+		want  string
+	}{
+		{
+			name:  "multibyte",
+			text:  "こんにちは\n世界\n",
+			edits: []lsp.TextEdit{edit(0, 2, 0, 5, "ばんは"), edit(1, 0, 1, 2, "地球")},
+			want:  "こんばんは\n地球\n",
+		},
+		{
+			name:  "inserts at same position",
+			text:  "fmt.Println()\n",
+			edits: []lsp.TextEdit{edit(0, 12, 0, 12, `"a"`), edit(0, 12, 0, 12, ", "), edit(0, 12, 0, 12, `"b"`)},
+			want:  "fmt.Println(\"a\", \"b\")\n",
+		},
+		{
+			name:  "empty edits",
+			text:  "package main\n",
+			edits: nil,
+			want:  "package main\n",
+		},
+	}
+	dir := t.TempDir()
+	for _, tt := range tests {
+		file := filepath.Join(dir, "a.go")
+		if err := os.WriteFile(file, []byte(tt.text), 0640); err != nil {
+			t.Fatal(err)
+		}
+		if err := applyFileEdits(file, tt.edits); err != nil {
+			t.Errorf("%s: applyFileEdits: %v", tt.name, err)
+			continue
+		}
+		b, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s := string(b); s != tt.want {
+			t.Errorf("%s: applyFileEdits = %q; want %q", tt.name, s, tt.want)
+		}
+		fi, err := os.Stat(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if m := fi.Mode().Perm(); m != 0640 {
+			t.Errorf("%s: mode = %v; want %v", tt.name, m, os.FileMode(0640))
+		}
+	}
+}
+
+func TestApplyFileEditsOutOfRange(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "a.go")
+	if err := os.WriteFile(file, []byte("a\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := applyFileEdits(file, []lsp.TextEdit{edit(5, 0, 5, 1, "x")}); err == nil {
+		t.Errorf("applyFileEdits: expected an error")
+	}
+	b, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := string(b); s != "a\n" {
+		t.Errorf("file = %q; want unchanged", s)
+	}
+}
+
+func TestCheckVersions(t *testing.T) {
+	versions := map[string]int{"/a.go": 3}
+	versionOf := func(file string) (int, bool) {
+		v, ok := versions[file]
+		return v, ok
+	}
+	change := func(file string, v *int) lsp.TextDocumentEdit {
+		var id lsp.VersionedTextDocumentIdentifier
+		id.URI = lsp.DocumentURI("file://" + file)
+		id.Version = v
+		return lsp.TextDocumentEdit{TextDocument: id}
+	}
+	version := func(n int) *int { return &n }
+	tests := []struct {
+		name    string
+		changes []lsp.TextDocumentEdit
+		wantErr bool
+	}{
+		{"same version", []lsp.TextDocumentEdit{change("/a.go", version(3))}, false},
+		{"stale version", []lsp.TextDocumentEdit{change("/a.go", version(2))}, true},
+		{"no version", []lsp.TextDocumentEdit{change("/a.go", nil)}, false},
+		{"not opened", []lsp.TextDocumentEdit{change("/b.go", version(1))}, false},
+		{"one of them is stale", []lsp.TextDocumentEdit{
+			change("/b.go", version(1)),
+			change("/a.go", version(4)),
+		}, true},
+	}
+	for _, tt := range tests {
+		err := checkVersions(tt.changes, versionOf)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: checkVersions = %v; want error %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestEchoesOf(t *testing.T) {
+	a := []textEdit{
+		{q0: 10, q1: 12, text: "世界"},
+		{q0: 5, q1: 5, text: "abc"},
+		{q0: 0, q1: 3, text: ""},
+	}
+	want := []echo{
+		{c2: 'D', q0: 10, q1: 12},
+		{c2: 'I', q0: 10, q1: 12},
+		{c2: 'I', q0: 5, q1: 8},
+		{c2: 'D', q0: 0, q1: 3},
+	}
+	if echoes := echoesOf(a); !reflect.DeepEqual(echoes, want) {
+		t.Errorf("echoesOf(%v) = %v; want %v", a, echoes, want)
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/lufia/acme-lsp/lsp"
+)
+
+// occurrence is a document highlight that is converted to offsets.
+type occurrence struct {
+	q0, q1 int
+	kind   lsp.DocumentHighlightKind
+}
+
+// occurrences returns occurrences of the symbol at q in w sorted by their offsets.
+func (w *Win) occurrences(q int) ([]occurrence, error) {
+	params, err := w.positionParams(q)
+	if err != nil {
+		return nil, err
+	}
+	result := w.srv.Client().DocumentHighlight(params)
+	ctx, cancel := newContext()
+	defer cancel()
+	if err := result.WaitContext(ctx); err != nil {
+		return nil, err
+	}
+	if len(result.Highlights) == 0 {
+		return nil, errors.New("no occurrences")
+	}
+	a := make([]occurrence, len(result.Highlights))
+	for i, h := range result.Highlights {
+		q0, q1, err := rangeOf(w.f, &h.Range)
+		if err != nil {
+			return nil, err
+		}
+		a[i] = occurrence{q0: q0, q1: q1, kind: h.Kind}
+	}
+	sort.Slice(a, func(i, j int) bool {
+		return a[i].q0 < a[j].q0
+	})
+	return a, nil
+}
+
+// ExecOccur prints addresses of all occurrences of the symbol at the cursor
+// with their kinds; text, read or write.
+func (w *Win) ExecOccur() error {
+	q, err := w.readCursor()
+	if err != nil {
+		return err
+	}
+	a, err := w.occurrences(q)
+	if err != nil {
+		return err
+	}
+	for _, o := range a {
+		w.acme.Errf("%s:#%d,#%d\t%s", w.file, o.q0, o.q1, o.kind)
+	}
+	return nil
+}
+
+// ExecNext selects the next occurrence of the symbol at the cursor.
+// After the last occurrence, it goes back to the first one.
+func (w *Win) ExecNext() error {
+	q, err := w.readCursor()
+	if err != nil {
+		return err
+	}
+	a, err := w.occurrences(q)
+	if err != nil {
+		return err
+	}
+	next := a[0]
+	for _, o := range a {
+		if o.q0 > q {
+			next = o
+			break
+		}
+	}
+	if err := w.acme.Addr("#%d,#%d", next.q0, next.q1); err != nil {
+		return err
+	}
+	w.acme.Ctl("dot=addr")
+	w.acme.Ctl("show")
+	return nil
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"path"
+	"strings"
+)
+
+// language is the configuration for documents of a language.
+type language struct {
+	ID string // languageId in the specification
+
+	// FormatOnPut reports whether the document is formatted before it is saved.
+	FormatOnPut bool
+
+	TabSize      int
+	InsertSpaces bool
+}
+
+// languages maps file extensions to their languages.
+var languages = map[string]*language{
+	".go": {
+		ID:      "go",
+		TabSize: 8,
+	},
+}
+
+// languageOf returns the language of file, or nil if it is not supported.
+func languageOf(file string) *language {
+	return languages[path.Ext(file)]
+}
+
+// setFormatOnPut enables FormatOnPut for languages listed in s separated by comma.
+func setFormatOnPut(s string) {
+	ids := make(map[string]bool)
+	for _, id := range strings.Split(s, ",") {
+		ids[strings.TrimSpace(id)] = true
+	}
+	for _, lang := range languages {
+		lang.FormatOnPut = ids[lang.ID]
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+const logWinName = "+lsplog"
+
+// crashPrefixes are beginnings of the line that Go runtime prints when a program crashed.
+var crashPrefixes = [][]byte{
+	[]byte("panic: "),
+	[]byte("fatal error: "),
+}
+
+// ServerLog writes outputs from the server to w line by line.
+// Crash reports are highlighted with the marker.
+//
+// ServerLog never fails to write, because the server would be killed by SIGPIPE
+// if the pipe of its stderr is closed. If w fails, lines are written to os.Stderr instead.
+type ServerLog struct {
+	mu       sync.Mutex
+	w        io.Writer
+	fallback io.Writer
+	failed   bool // whether the failure of w is reported
+	buf      []byte
+}
+
+// NewServerLog returns a ServerLog that writes to w.
+func NewServerLog(w io.Writer) *ServerLog {
+	return &ServerLog{w: w, fallback: os.Stderr}
+}
+
+// Write implements io.Writer interface.
+// It always consumes whole p even if the underlying writer fails.
+func (l *ServerLog) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.buf = append(l.buf, p...)
+	for {
+		i := bytes.IndexByte(l.buf, '\n')
+		if i < 0 {
+			break
+		}
+		line := l.buf[:i+1]
+		if isCrash(line) {
+			l.writeLine([]byte("*** server crashed ***\n"))
+			if s, ok := l.w.(interface{ Show() }); ok {
+				s.Show()
+			}
+		}
+		l.writeLine(line)
+		l.buf = l.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+// writeLine writes line to l.w, or l.fallback if l.w fails.
+func (l *ServerLog) writeLine(line []byte) {
+	_, err := l.w.Write(line)
+	if err == nil {
+		return
+	}
+	if !l.failed {
+		l.failed = true
+		fmt.Fprintf(l.fallback, "acme-lsp: can't write the server log: %v\n", err)
+	}
+	l.fallback.Write(line)
+}
+
+func isCrash(line []byte) bool {
+	for _, s := range crashPrefixes {
+		if bytes.HasPrefix(line, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// LogWin is a writer that appends texts into the acme window named +lsplog.
+// The window is created when it is written at first time.
+type LogWin struct {
+	name string
+}
+
+// NewLogWin returns a LogWin that is placed at current directory.
+func NewLogWin() *LogWin {
+	dir, err := os.Getwd()
+	if err != nil {
+		dir = "."
+	}
+	return &LogWin{name: filepath.Join(dir, logWinName)}
+}
+
+// Write implements io.Writer interface.
+func (w *LogWin) Write(p []byte) (int, error) {
+	win, err := openWin(w.name)
+	if err != nil {
+		return 0, err
+	}
+	win.Addr("$")
+	n, err := win.Write("data", p)
+	win.Ctl("clean")
+	return n, err
+}
+
+// Show shows the window in acme.
+func (w *LogWin) Show() {
+	if win, err := openWin(w.name); err == nil {
+		win.Ctl("show")
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+type errWriter struct{}
+
+func (errWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("closed")
+}
+
+func TestServerLogFailure(t *testing.T) {
+	var fallback bytes.Buffer
+	l := NewServerLog(errWriter{})
+	l.fallback = &fallback
+	for _, s := range []string{"first ", "line\n", "second line\n"} {
+		n, err := l.Write([]byte(s))
+		if n != len(s) || err != nil {
+			t.Errorf("Write(%q) = %d, %v; want %d, nil", s, n, err, len(s))
+		}
+	}
+	want := "acme-lsp: can't write the server log: closed\nfirst line\nsecond line\n"
+	if s := fallback.String(); s != want {
+		t.Errorf("fallback = %q; want %q", s, want)
+	}
+}
+
+func TestServerLogCrash(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewServerLog(&buf)
+	s := "log\npanic: oops\n"
+	if n, err := l.Write([]byte(s)); n != len(s) || err != nil {
+		t.Errorf("Write(%q) = %d, %v; want %d, nil", s, n, err, len(s))
+	}
+	want := "log\n*** server crashed ***\npanic: oops\n"
+	if s := buf.String(); s != want {
+		t.Errorf("log = %q; want %q", s, want)
+	}
+}
@@ -0,0 +1,167 @@
+package lsp_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/lufia/acme-lsp/lsp"
+)
+
+func TestCodeActionUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want lsp.CodeAction
+	}{
+		{
+			name: "Command",
+			data: `{"title":"Organize Imports","command":"organize","arguments":["a"]}`,
+			want: lsp.CodeAction{
+				Title: "Organize Imports",
+				Command: &lsp.Command{
+					Title:     "Organize Imports",
+					Command:   "organize",
+					Arguments: []json.RawMessage{json.RawMessage(`"a"`)},
+				},
+			},
+		},
+		{
+			name: "CodeAction with data",
+			data: `{"title":"Fill struct","kind":"refactor.rewrite","data":{"id":1}}`,
+			want: lsp.CodeAction{
+				Title: "Fill struct",
+				Kind:  lsp.CodeActionKindRefactorRewrite,
+				Data:  json.RawMessage(`{"id":1}`),
+			},
+		},
+		{
+			name: "CodeAction with command",
+			data: `{"title":"Remove","kind":"quickfix","command":{"title":"Remove","command":"remove"},"disabled":{"reason":"no"}}`,
+			want: lsp.CodeAction{
+				Title:   "Remove",
+				Kind:    lsp.CodeActionKindQuickFix,
+				Command: &lsp.Command{Title: "Remove", Command: "remove"},
+				Disabled: &struct {
+					Reason string `json:"reason"`
+				}{Reason: "no"},
+			},
+		},
+		{
+			name: "CodeAction with edit",
+			data: `{"title":"Fix","isPreferred":true,"edit":{"changes":{"file:///a.go":[]}}}`,
+			want: lsp.CodeAction{
+				Title:       "Fix",
+				IsPreferred: true,
+				Edit: &lsp.WorkspaceEdit{
+					Changes: map[lsp.DocumentURI][]lsp.TextEdit{"file:///a.go": {}},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		var a lsp.CodeAction
+		if err := json.Unmarshal([]byte(tt.data), &a); err != nil {
+			t.Errorf("%s: Unmarshal: %v", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(a, tt.want) {
+			t.Errorf("%s: Unmarshal = %+v; want %+v", tt.name, a, tt.want)
+		}
+	}
+}
+
+func TestCodeActionOptionsUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		data string
+		want lsp.CodeActionOptions
+	}{
+		{`false`, lsp.CodeActionOptions{}},
+		{`true`, lsp.CodeActionOptions{Enabled: true}},
+		{`{}`, lsp.CodeActionOptions{Enabled: true}},
+		{
+			`{"codeActionKinds":["quickfix"],"resolveProvider":true}`,
+			lsp.CodeActionOptions{Enabled: true, CodeActionKinds: []string{"quickfix"}, ResolveProvider: true},
+		},
+	}
+	for _, tt := range tests {
+		var o lsp.CodeActionOptions
+		if err := json.Unmarshal([]byte(tt.data), &o); err != nil {
+			t.Errorf("Unmarshal(%s): %v", tt.data, err)
+			continue
+		}
+		if !reflect.DeepEqual(o, tt.want) {
+			t.Errorf("Unmarshal(%s) = %+v; want %+v", tt.data, o, tt.want)
+		}
+	}
+}
+
+func TestCodeActionRequests(t *testing.T) {
+	c, s := newTestClient(t)
+	s.HandleResult("textDocument/codeAction", json.RawMessage(`[{"title":"Fill struct"}]`))
+	s.HandleResult("codeAction/resolve", json.RawMessage(`{"title":"Fill struct","edit":{}}`))
+	s.HandleResult("workspace/executeCommand", nil)
+	tests := []struct {
+		method string
+		call   func() error
+		params string
+	}{
+		{
+			method: "textDocument/codeAction",
+			call: func() error {
+				r := c.CodeAction(&lsp.CodeActionParams{
+					TextDocument: lsp.TextDocumentIdentifier{URI: "file:///a.go"},
+					Context: lsp.CodeActionContext{
+						Diagnostics: []lsp.Diagnostic{{Message: "unused"}},
+					},
+				})
+				if err := r.Wait(); err != nil {
+					return err
+				}
+				if len(r.Actions) != 1 {
+					t.Errorf("Actions = %+v; want 1 action", r.Actions)
+				}
+				return nil
+			},
+			params: `{"textDocument":{"uri":"file:///a.go"},"range":{"start":{"line":0,"character":0},"end":{"line":0,"character":0}},"context":{"diagnostics":[{"range":{"start":{"line":0,"character":0},"end":{"line":0,"character":0}},"message":"unused"}]}}`,
+		},
+		{
+			method: "codeAction/resolve",
+			call: func() error {
+				r := c.ResolveCodeAction(&lsp.CodeAction{Title: "Fill struct", Data: json.RawMessage(`1`)})
+				if err := r.Wait(); err != nil {
+					return err
+				}
+				if r.Action.Edit == nil {
+					t.Errorf("Edit = nil; want an empty edit")
+				}
+				return nil
+			},
+			params: `{"title":"Fill struct","data":1}`,
+		},
+		{
+			method: "workspace/executeCommand",
+			call: func() error {
+				return c.ExecuteCommand(&lsp.ExecuteCommandParams{
+					Command:   "organize",
+					Arguments: []json.RawMessage{json.RawMessage(`"a"`)},
+				}).Wait()
+			},
+			params: `{"command":"organize","arguments":["a"]}`,
+		},
+	}
+	for _, tt := range tests {
+		if err := tt.call(); err != nil {
+			t.Errorf("%s: %v", tt.method, err)
+			continue
+		}
+		a := s.Messages(tt.method)
+		if len(a) != 1 {
+			t.Errorf("received %d %s requests; want 1", len(a), tt.method)
+			continue
+		}
+		if s := string(a[0].Params); s != tt.params {
+			t.Errorf("%s: params = %s; want %s", tt.method, s, tt.params)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package lsp_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/lufia/acme-lsp/lsp"
+)
+
+const testCallItemJSON = `{"name":"F","kind":12,"uri":"file:///pkg.go",` +
+	`"range":{"start":{"line":2,"character":0},"end":{"line":4,"character":1}},` +
+	`"selectionRange":{"start":{"line":2,"character":5},"end":{"line":2,"character":6}},` +
+	`"data":{"id":1}}`
+
+var testCallItem = lsp.CallHierarchyItem{
+	Name: "F",
+	Kind: lsp.SymbolKindFunction,
+	URI:  "file:///pkg.go",
+	Range: lsp.Range{
+		Start: lsp.Position{Line: 2, Character: 0},
+		End:   lsp.Position{Line: 4, Character: 1},
+	},
+	SelectionRange: lsp.Range{
+		Start: lsp.Position{Line: 2, Character: 5},
+		End:   lsp.Position{Line: 2, Character: 6},
+	},
+	Data: json.RawMessage(`{"id":1}`),
+}
+
+func TestCallHierarchyRequests(t *testing.T) {
+	c, s := newTestClient(t)
+	s.HandleResult("textDocument/prepareCallHierarchy", json.RawMessage(`[`+testCallItemJSON+`]`))
+	s.HandleResult("callHierarchy/incomingCalls", json.RawMessage(`[]`))
+	s.HandleResult("callHierarchy/outgoingCalls", json.RawMessage(`[]`))
+	tests := []struct {
+		method string
+		call   func() error
+		params string
+	}{
+		{
+			method: "textDocument/prepareCallHierarchy",
+			call: func() error {
+				return c.PrepareCallHierarchy(&lsp.TextDocumentPositionParams{
+					TextDocument: lsp.TextDocumentIdentifier{URI: "file:///pkg.go"},
+					Position:     lsp.Position{Line: 2, Character: 5},
+				}).Wait()
+			},
+			params: `{"textDocument":{"uri":"file:///pkg.go"},"position":{"line":2,"character":5}}`,
+		},
+		{
+			// the item, including its data, must be sent back as it is.
+			method: "callHierarchy/incomingCalls",
+			call: func() error {
+				return c.IncomingCalls(&lsp.CallHierarchyIncomingCallsParams{Item: testCallItem}).Wait()
+			},
+			params: `{"item":` + testCallItemJSON + `}`,
+		},
+		{
+			method: "callHierarchy/outgoingCalls",
+			call: func() error {
+				return c.OutgoingCalls(&lsp.CallHierarchyOutgoingCallsParams{Item: testCallItem}).Wait()
+			},
+			params: `{"item":` + testCallItemJSON + `}`,
+		},
+	}
+	for _, tt := range tests {
+		if err := tt.call(); err != nil {
+			t.Errorf("%s: %v", tt.method, err)
+			continue
+		}
+		a := s.Messages(tt.method)
+		if len(a) != 1 {
+			t.Errorf("received %d %s requests; want 1", len(a), tt.method)
+			continue
+		}
+		if s := string(a[0].Params); s != tt.params {
+			t.Errorf("%s: params = %s; want %s", tt.method, s, tt.params)
+		}
+	}
+}
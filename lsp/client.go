@@ -4,14 +4,18 @@ package lsp
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/url"
 	"os"
 	"os/exec"
 	"strconv"
 	"strings"
+	"sync"
 )
 
 // PipeConn represents a connection to a process.
@@ -23,7 +27,14 @@ type PipeConn struct {
 
 // OpenCommand returns a connection to executing command.
 func OpenCommand(name string, args ...string) (*PipeConn, error) {
-	cmd := exec.Command(name, args...)
+	return StartCommand(exec.Command(name, args...))
+}
+
+// StartCommand starts cmd, then returns a connection to it.
+// Stdin and stdout of cmd are used to communicate with the server,
+// so these must not be set. If cmd.Stderr is set,
+// outputs from the server, such as logs or panics, are written to it.
+func StartCommand(cmd *exec.Cmd) (*PipeConn, error) {
 	w, err := cmd.StdinPipe()
 	if err != nil {
 		return nil, fmt.Errorf("can't pipe: %w", err)
@@ -36,7 +47,7 @@ func OpenCommand(name string, args ...string) (*PipeConn, error) {
 	if err := cmd.Start(); err != nil {
 		r.Close()
 		w.Close()
-		return nil, fmt.Errorf("can't start gopls: %w", err)
+		return nil, fmt.Errorf("can't start %s: %w", cmd.Path, err)
 	}
 	return &PipeConn{cmd: cmd, r: r, w: w}, nil
 }
@@ -74,14 +85,81 @@ func (c *PipeConn) Close() error {
 	return err
 }
 
+// Dial returns a connection to the server listening on addr.
+// Addr is a TCP address such as "localhost:37374",
+// or a path of unix domain socket prefixed with "unix;",
+// same as -remote flag of gopls.
+func Dial(addr string) (net.Conn, error) {
+	network := "tcp"
+	if s := strings.TrimPrefix(addr, "unix;"); s != addr {
+		network = "unix"
+		addr = s
+	} else {
+		addr = strings.TrimPrefix(addr, "tcp;")
+	}
+	conn, err := net.Dial(network, addr)
+	if err != nil {
+		return nil, fmt.Errorf("can't dial: %w", err)
+	}
+	return conn, nil
+}
+
+// ID represents an id of the request. It is either a number or a string.
+type ID struct {
+	num   int64
+	str   string
+	isStr bool
+}
+
+// NumberID returns an ID represented as a number.
+func NumberID(n int64) ID {
+	return ID{num: n}
+}
+
+// StringID returns an ID represented as a string.
+func StringID(s string) ID {
+	return ID{str: s, isStr: true}
+}
+
+// String returns a representation of id for debugging.
+func (id ID) String() string {
+	if id.isStr {
+		return strconv.Quote(id.str)
+	}
+	return strconv.FormatInt(id.num, 10)
+}
+
+// MarshalJSON implements json.Marshaler interface.
+func (id ID) MarshalJSON() ([]byte, error) {
+	if id.isStr {
+		return json.Marshal(id.str)
+	}
+	return json.Marshal(id.num)
+}
+
+// UnmarshalJSON implements json.Unmarshaler interface.
+func (id *ID) UnmarshalJSON(data []byte) error {
+	var n int64
+	if err := json.Unmarshal(data, &n); err == nil {
+		*id = NumberID(n)
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("id must be a number or a string: %s", data)
+	}
+	*id = StringID(s)
+	return nil
+}
+
 // Message represents request/response/notification messages.
-// If Params is not nil, client treats the message as a request.
-// If Result or Error is not nil, client treats the message as a response.
-// In addition to the above, If ID set to zero, client treats it as a notification.
+// If Method is not empty, the message is a request if ID is not nil,
+// otherwise it is a notification.
+// If Method is empty, the message is a response.
 type Message struct {
 	Version string `json:"jsonrpc"`
-	ID      int    `json:"id,omitempty"`
-	Method  string `json:"method"`
+	ID      *ID    `json:"id,omitempty"`
+	Method  string `json:"method,omitempty"`
 
 	// This appears request or notification.
 	Params json.RawMessage `json:"params,omitempty"`
@@ -95,7 +173,7 @@ type Message struct {
 type ResponseError struct {
 	Code    int             `json:"code"`
 	Message string          `json:"message"`
-	Data    json.RawMessage `json:"data"`
+	Data    json.RawMessage `json:"data,omitempty"`
 }
 
 // Error implements error interface.
@@ -103,6 +181,31 @@ func (e *ResponseError) Error() string {
 	return fmt.Sprintf("%d: %s", e.Code, e.Message)
 }
 
+// Error codes defined in the specification.
+const (
+	ErrorCodeParseError     = -32700
+	ErrorCodeInvalidRequest = -32600
+	ErrorCodeMethodNotFound = -32601
+	ErrorCodeInvalidParams  = -32602
+	ErrorCodeInternalError  = -32603
+)
+
+// Handler responds to a request from the server.
+// The returned value is sent to the server as the result of the request.
+// If Handle returns an error, it is sent as the error of the request instead;
+// *ResponseError is sent as is, other errors are sent as an internal error.
+type Handler interface {
+	Handle(method string, params json.RawMessage) (interface{}, error)
+}
+
+// The HandlerFunc type is an adapter to allow the use of ordinary functions as Handler.
+type HandlerFunc func(method string, params json.RawMessage) (interface{}, error)
+
+// Handle calls f(method, params).
+func (f HandlerFunc) Handle(method string, params json.RawMessage) (interface{}, error) {
+	return f(method, params)
+}
+
 // Call represents an active rpc.
 type Call struct {
 	Method string
@@ -115,14 +218,29 @@ type Call struct {
 }
 
 // Client represents a language server protocol client.
+// Requests from the server are passed to Handler;
+// if Handler is nil, the client responds MethodNotFound error to them.
+//
+// Notifications from the server are passed to handlers registered with OnNotification.
+// Notifications that have no handler are sent to Event,
+// but they are dropped while Event is full.
 type Client struct {
 	BaseURL *url.URL
 	Event   chan *Message
+	Handler Handler
 	Debug   bool
 
-	lastID int
-	conn   io.ReadWriteCloser
-	c      chan *Call
+	lastID  int64 // used only in run
+	conn    io.ReadWriteCloser
+	c       chan *Call
+	cancelc chan *Call
+	respc   chan *Message
+	done    chan struct{}
+	err     error // valid after done is closed
+
+	nmu       sync.Mutex // protects notifiers
+	notifiers map[string]func(params json.RawMessage)
+	q         *notifyQueue
 
 	cap ServerCapabilities
 }
@@ -131,11 +249,18 @@ type Client struct {
 // This method starts goroutines, so you must call Close method after use.
 func NewClient(conn io.ReadWriteCloser) *Client {
 	c := &Client{
-		Event: make(chan *Message, 10),
-		conn:  conn,
-		c:     make(chan *Call),
+		Event:   make(chan *Message, 10),
+		conn:    conn,
+		c:       make(chan *Call),
+		cancelc: make(chan *Call),
+		respc:   make(chan *Message),
+		done:    make(chan struct{}),
+
+		notifiers: make(map[string]func(params json.RawMessage)),
+		q:         newNotifyQueue(),
 	}
 	go c.run()
+	go c.dispatch()
 	return c
 }
 
@@ -162,7 +287,12 @@ func (c *Client) Call(method string, args, reply interface{}) *Call {
 		return call
 	}
 	call.msg = r
-	c.c <- call
+	select {
+	case c.c <- call:
+	case <-c.done:
+		call.Error = c.err
+		call.done <- call
+	}
 	return call
 }
 
@@ -171,14 +301,8 @@ func (c *Client) makeRequest(method string, args, reply interface{}) (*Message,
 	if err != nil {
 		return nil, err
 	}
-	var id int
-	if reply != nil {
-		c.lastID++
-		id = c.lastID
-	}
 	return &Message{
 		Version: "2.0",
-		ID:      id,
 		Method:  method,
 		Params:  json.RawMessage(params),
 	}, nil
@@ -187,23 +311,43 @@ func (c *Client) makeRequest(method string, args, reply interface{}) (*Message,
 // Wait waits for a response of call.
 // This is low level API.
 func (c *Client) Wait(call *Call) error {
-	call = <-call.done
+	return c.WaitContext(context.Background(), call)
+}
+
+// WaitContext is like Wait but gives up waiting when ctx is done.
+// In that case, the client sends $/cancelRequest to the server,
+// forgets the call, and returns ctx.Err().
+// This is low level API.
+func (c *Client) WaitContext(ctx context.Context, call *Call) error {
+	select {
+	case call = <-call.done:
+	case <-ctx.Done():
+		if call.msg != nil {
+			select {
+			case c.cancelc <- call:
+			case <-c.done:
+			}
+		}
+		return ctx.Err()
+	}
 	if call.Error != nil {
 		return call.Error
 	}
 	return nil
 }
 
+// CancelParams represents the interface described in the specification.
+type CancelParams struct {
+	ID ID `json:"id"`
+}
+
 func (c *Client) reader(replyc chan<- *Message) {
 	defer close(replyc)
 	r := bufio.NewReader(c.conn)
 	for {
 		msg, err := c.readMessage(r)
-		if err == io.EOF {
-			return
-		}
 		if err != nil {
-			// TODO(lufia): where do we pass an error?
+			c.err = err
 			return
 		}
 		replyc <- msg
@@ -211,19 +355,35 @@ func (c *Client) reader(replyc chan<- *Message) {
 }
 
 func (c *Client) run() {
-	callc := c.c
 	replyc := make(chan *Message, 1)
 	go c.reader(replyc)
 
-	cache := make(map[int]*Call)
-	for callc != nil || replyc != nil {
+	cache := make(map[ID]*Call)
+	defer func() {
+		// c.err is set by reader before replyc is closed.
+		close(c.done)
+		for _, call := range cache {
+			call.Error = c.err
+			call.done <- call
+		}
+		c.q.close()
+		close(c.Event)
+	}()
+	for {
 		select {
 		case msg, ok := <-replyc:
 			if !ok {
-				replyc = nil
+				return
+			}
+			if msg.Method != "" && msg.ID != nil { // request from the server
+				go c.handle(msg)
 				continue
 			}
-			if msg.Params != nil { // request from the server
+			if msg.Method != "" { // notification from the server
+				if c.notifier(msg.Method) != nil {
+					c.q.push(msg)
+					continue
+				}
 				// shouldn't block even if c.Event is full.
 				select {
 				case c.Event <- msg:
@@ -232,11 +392,15 @@ func (c *Client) run() {
 				continue
 			}
 
-			call := cache[msg.ID]
+			if msg.ID == nil {
+				// error response for a request that the server couldn't parse.
+				continue
+			}
+			call := cache[*msg.ID]
 			if call == nil {
 				continue
 			}
-			delete(cache, msg.ID)
+			delete(cache, *msg.ID)
 			if msg.Error != nil {
 				call.Error = msg.Error
 				call.done <- call
@@ -249,24 +413,83 @@ func (c *Client) run() {
 				continue
 			}
 			call.done <- call
-		case call, ok := <-callc:
-			if !ok {
-				callc = nil
-				continue
+		case call := <-c.c:
+			if call.Reply != nil {
+				c.lastID++
+				id := NumberID(c.lastID)
+				call.msg.ID = &id
 			}
 			if err := c.writeJSON(call.msg); err != nil {
 				call.Error = err
 				call.done <- call
 				continue
 			}
-			if call.msg.ID == 0 {
+			if call.msg.ID == nil {
 				call.done <- call
 				continue
 			}
-			cache[call.msg.ID] = call
+			cache[*call.msg.ID] = call
+		case call := <-c.cancelc:
+			if call.msg.ID == nil {
+				continue
+			}
+			id := *call.msg.ID
+			if cache[id] != call {
+				continue
+			}
+			delete(cache, id)
+			msg, err := c.makeRequest("$/cancelRequest", &CancelParams{ID: id}, nil)
+			if err != nil {
+				continue
+			}
+			c.writeJSON(msg)
+		case msg := <-c.respc:
+			c.writeJSON(msg)
+		}
+	}
+}
+
+func (c *Client) handle(req *Message) {
+	var (
+		result interface{}
+		err    error
+	)
+	if c.Handler != nil {
+		result, err = c.Handler.Handle(req.Method, req.Params)
+	} else {
+		err = &ResponseError{
+			Code:    ErrorCodeMethodNotFound,
+			Message: fmt.Sprintf("method not found: %s", req.Method),
+		}
+	}
+	resp := &Message{
+		Version: "2.0",
+		ID:      req.ID,
+	}
+	if err != nil {
+		var e *ResponseError
+		if !errors.As(err, &e) {
+			e = &ResponseError{
+				Code:    ErrorCodeInternalError,
+				Message: err.Error(),
+			}
 		}
+		resp.Error = e
+	} else {
+		p, err := json.Marshal(result)
+		if err != nil {
+			resp.Error = &ResponseError{
+				Code:    ErrorCodeInternalError,
+				Message: fmt.Sprintf("can't marshal: %v", err),
+			}
+		} else {
+			resp.Result = json.RawMessage(p)
+		}
+	}
+	select {
+	case c.respc <- resp:
+	case <-c.done:
 	}
-	close(c.Event)
 }
 
 func (c *Client) readMessage(r *bufio.Reader) (*Message, error) {
@@ -320,8 +543,25 @@ func (c *Client) writeJSON(args interface{}) error {
 	return nil
 }
 
+// Done returns a channel that is closed when the connection to the server is terminated.
+func (c *Client) Done() <-chan struct{} {
+	return c.done
+}
+
+// Err returns the error that terminated the connection to the server.
+// It returns io.EOF if the server closed the connection.
+// While the connection is alive, Err returns nil.
+func (c *Client) Err() error {
+	select {
+	case <-c.done:
+		return c.err
+	default:
+		return nil
+	}
+}
+
 // Close closes underlying resources such as a connection and goroutines.
+// After Close, all calls fail with Err.
 func (c *Client) Close() error {
-	close(c.c)
 	return c.conn.Close()
 }
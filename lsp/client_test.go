@@ -1,10 +1,14 @@
 package lsp
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"io/ioutil"
+	"net"
+	"os"
 	"os/exec"
+	"path/filepath"
 	"testing"
 )
 
@@ -295,3 +299,147 @@ func TestClientEventOverflow(t *testing.T) {
 		t.Errorf("Wait(): %v", err)
 	}
 }
+
+func TestDial(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		network string
+		addr    string
+		prefix  string
+	}{
+		{network: "tcp", addr: "127.0.0.1:0"},
+		{network: "tcp", addr: "127.0.0.1:0", prefix: "tcp;"},
+		{network: "unix", addr: filepath.Join(dir, "lsp.sock"), prefix: "unix;"},
+	}
+	for _, tt := range tests {
+		l, err := net.Listen(tt.network, tt.addr)
+		if err != nil {
+			t.Fatalf("Listen(%q, %q): %v", tt.network, tt.addr, err)
+		}
+		go func() {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			conn.Write([]byte("ok"))
+			conn.Close()
+		}()
+		addr := tt.prefix + l.Addr().String()
+		conn, err := Dial(addr)
+		if err != nil {
+			t.Errorf("Dial(%q): %v", addr, err)
+			l.Close()
+			continue
+		}
+		b, err := ioutil.ReadAll(conn)
+		if err != nil || string(b) != "ok" {
+			t.Errorf("Dial(%q): read %q, %v; want ok", addr, b, err)
+		}
+		conn.Close()
+		l.Close()
+	}
+}
+
+func TestStartCommandStderr(t *testing.T) {
+	cmd := exec.Command("sh", "-c", "echo panic: test >&2")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	conn, err := StartCommand(cmd)
+	if err != nil {
+		if errors.Is(err, exec.ErrNotFound) {
+			t.Skip()
+		}
+		t.Fatal(err)
+	}
+	if _, err := ioutil.ReadAll(conn); err != nil {
+		t.Errorf("ReadAll: %v", err)
+	}
+	conn.Close()
+	if s := stderr.String(); s != "panic: test\n" {
+		t.Errorf("Stderr = %q; want %q", s, "panic: test\n")
+	}
+}
+
+func TestMessageID(t *testing.T) {
+	num := NumberID(1)
+	str := StringID("abc")
+	tests := []struct {
+		body string
+		id   *ID
+	}{
+		{body: `{"jsonrpc":"2.0","id":1,"method":"test","params":{}}`, id: &num},
+		{body: `{"jsonrpc":"2.0","id":"abc","method":"test","params":{}}`, id: &str},
+		{body: `{"jsonrpc":"2.0","method":"test","params":{}}`},
+	}
+	for _, tt := range tests {
+		var msg Message
+		if err := json.Unmarshal([]byte(tt.body), &msg); err != nil {
+			t.Fatalf("can't unmarshal: '%v': %v", tt.body, err)
+		}
+		switch {
+		case tt.id == nil && msg.ID != nil:
+			t.Errorf("Unmarshal('%v').ID = %v; want nil", tt.body, *msg.ID)
+		case tt.id != nil && (msg.ID == nil || *msg.ID != *tt.id):
+			t.Errorf("Unmarshal('%v').ID = %v; want %v", tt.body, msg.ID, *tt.id)
+		}
+		p, err := json.Marshal(&msg)
+		if err != nil {
+			t.Fatalf("can't marshal: %v", err)
+		}
+		if s := string(p); s != tt.body {
+			t.Errorf("Marshal() = '%s'; want '%s'", s, tt.body)
+		}
+	}
+
+	var id ID
+	if err := json.Unmarshal([]byte(`{}`), &id); err == nil {
+		t.Errorf("Unmarshal('{}') should fail")
+	}
+}
+
+func TestReplayer(t *testing.T) {
+	f, err := os.Open("testdata/definition.trace")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	conn, err := NewReplayer(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewClient(conn)
+	defer c.Close()
+	c.SetRootURI("/tmp/pkg1")
+
+	result := c.Initialize(&InitializeParams{
+		RootURI: c.URL("."),
+	})
+	if err := result.Wait(); err != nil {
+		t.Fatalf("Initialize: %v", err)
+	}
+	if !result.Capabilities.DefinitionProvider {
+		t.Errorf("DefinitionProvider = false; want true")
+	}
+	if err := c.Initialized(&InitializedParams{}); err != nil {
+		t.Fatalf("Initialized: %v", err)
+	}
+	r := c.GotoDefinition(&TextDocumentPositionParams{
+		TextDocument: TextDocumentIdentifier{URI: c.URL("pkg.go")},
+		Position:     Position{Line: 11, Character: 10},
+	})
+	if err := r.Wait(); err != nil {
+		t.Fatalf("GotoDefinition: %v", err)
+	}
+	want := Range{
+		Start: Position{Line: 6, Character: 5},
+		End:   Position{Line: 6, Character: 13},
+	}
+	if n := len(r.Locations); n != 1 || r.Locations[0].Range != want {
+		t.Errorf("Locations = %v; want a location at %v", r.Locations, want)
+	}
+
+	// the trace don't have any more messages.
+	if err := c.Shutdown().Wait(); err == nil {
+		t.Errorf("Shutdown: want an error")
+	}
+}
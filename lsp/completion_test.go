@@ -0,0 +1,159 @@
+package lsp_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/lufia/acme-lsp/lsp"
+)
+
+func TestCompletionListUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want lsp.CompletionList
+	}{
+		{
+			name: "array",
+			data: `[{"label":"Println"},{"label":"Printf"}]`,
+			want: lsp.CompletionList{
+				Items: []lsp.CompletionItem{{Label: "Println"}, {Label: "Printf"}},
+			},
+		},
+		{
+			name: "list",
+			data: `{"isIncomplete":true,"items":[{"label":"Println"}]}`,
+			want: lsp.CompletionList{
+				IsIncomplete: true,
+				Items:        []lsp.CompletionItem{{Label: "Println"}},
+			},
+		},
+		{
+			name: "empty array",
+			data: `[]`,
+			want: lsp.CompletionList{Items: []lsp.CompletionItem{}},
+		},
+		{
+			name: "null",
+			data: `null`,
+			want: lsp.CompletionList{},
+		},
+	}
+	for _, tt := range tests {
+		var l lsp.CompletionList
+		if err := json.Unmarshal([]byte(tt.data), &l); err != nil {
+			t.Errorf("%s: Unmarshal: %v", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(l, tt.want) {
+			t.Errorf("%s: Unmarshal = %+v; want %+v", tt.name, l, tt.want)
+		}
+	}
+}
+
+func TestCompletionItemUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want lsp.CompletionItem
+	}{
+		{
+			name: "documentation as string",
+			data: `{"label":"Println","documentation":"prints a line."}`,
+			want: lsp.CompletionItem{
+				Label:         "Println",
+				Documentation: &lsp.MarkupContent{Kind: lsp.MarkupKindPlainText, Value: "prints a line."},
+			},
+		},
+		{
+			name: "documentation as MarkupContent",
+			data: `{"label":"Println","documentation":{"kind":"markdown","value":"*prints*"}}`,
+			want: lsp.CompletionItem{
+				Label:         "Println",
+				Documentation: &lsp.MarkupContent{Kind: lsp.MarkupKindMarkdown, Value: "*prints*"},
+			},
+		},
+		{
+			name: "snippet with textEdit",
+			data: `{"label":"Println","insertTextFormat":2,"textEdit":{"range":{"start":{"line":1,"character":4},"end":{"line":1,"character":6}},"newText":"Println(${1})"}}`,
+			want: lsp.CompletionItem{
+				Label:            "Println",
+				InsertTextFormat: lsp.InsertTextFormatSnippet,
+				TextEdit: &lsp.TextEdit{
+					Range: lsp.Range{
+						Start: lsp.Position{Line: 1, Character: 4},
+						End:   lsp.Position{Line: 1, Character: 6},
+					},
+					NewText: "Println(${1})",
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		var item lsp.CompletionItem
+		if err := json.Unmarshal([]byte(tt.data), &item); err != nil {
+			t.Errorf("%s: Unmarshal: %v", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(item, tt.want) {
+			t.Errorf("%s: Unmarshal = %+v; want %+v", tt.name, item, tt.want)
+		}
+	}
+}
+
+func TestCompletion(t *testing.T) {
+	c, s := newTestClient(t)
+	s.HandleResult("textDocument/completion", json.RawMessage(`[{"label":"Println"}]`))
+	r := c.Completion(&lsp.CompletionParams{
+		TextDocumentPositionParams: lsp.TextDocumentPositionParams{
+			TextDocument: lsp.TextDocumentIdentifier{URI: c.URL("pkg.go")},
+		},
+		Context: &lsp.CompletionContext{
+			TriggerKind: lsp.CompletionTriggerKindInvoked,
+		},
+	})
+	if err := r.Wait(); err != nil {
+		t.Fatalf("Completion: %v", err)
+	}
+	if len(r.List.Items) != 1 || r.List.Items[0].Label != "Println" {
+		t.Errorf("Items = %+v; want Println", r.List.Items)
+	}
+	a := s.Messages("textDocument/completion")
+	if len(a) != 1 {
+		t.Fatalf("received %d completion requests; want 1", len(a))
+	}
+	var p struct {
+		Context struct {
+			TriggerKind int `json:"triggerKind"`
+		} `json:"context"`
+	}
+	if err := json.Unmarshal(a[0].Params, &p); err != nil {
+		t.Fatal(err)
+	}
+	if p.Context.TriggerKind != lsp.CompletionTriggerKindInvoked {
+		t.Errorf("triggerKind = %d; want %d", p.Context.TriggerKind, lsp.CompletionTriggerKindInvoked)
+	}
+}
+
+func TestResolveCompletionItem(t *testing.T) {
+	c, s := newTestClient(t)
+	s.Handle("completionItem/resolve", func(params json.RawMessage) (interface{}, error) {
+		var item lsp.CompletionItem
+		if err := json.Unmarshal(params, &item); err != nil {
+			return nil, err
+		}
+		return json.RawMessage(`{"label":"` + item.Label + `","documentation":"prints a line.","data":1}`), nil
+	})
+	r := c.ResolveCompletionItem(&lsp.CompletionItem{Label: "Println"})
+	if err := r.Wait(); err != nil {
+		t.Fatalf("ResolveCompletionItem: %v", err)
+	}
+	want := lsp.MarkupContent{Kind: lsp.MarkupKindPlainText, Value: "prints a line."}
+	if d := r.Item.Documentation; d == nil || *d != want {
+		t.Errorf("Documentation = %v; want %v", d, want)
+	}
+	if r.Item.Label != "Println" || string(r.Item.Data) != "1" {
+		t.Errorf("Item = %+v; want Label = Println, Data = 1", r.Item)
+	}
+}
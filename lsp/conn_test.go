@@ -0,0 +1,205 @@
+package lsp_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/lufia/acme-lsp/lsp"
+	"github.com/lufia/acme-lsp/lsp/lsptest"
+)
+
+func newTestClient(t *testing.T) (*lsp.Client, *lsptest.Server) {
+	t.Helper()
+	s := lsptest.NewServer()
+	c := lsp.NewClient(s.Conn())
+	c.Debug = testing.Verbose()
+	t.Cleanup(func() {
+		c.Close()
+		s.Close()
+	})
+	return c, s
+}
+
+// blockHandler returns a handler that responds result after release is closed.
+func blockHandler(release <-chan struct{}, result interface{}) lsptest.HandlerFunc {
+	return func(params json.RawMessage) (interface{}, error) {
+		<-release
+		return result, nil
+	}
+}
+
+func TestWaitContextCancel(t *testing.T) {
+	c, s := newTestClient(t)
+	release := make(chan struct{})
+	s.Handle("textDocument/definition", blockHandler(release, json.RawMessage(`[]`)))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	result := c.GotoDefinition(&lsp.TextDocumentPositionParams{
+		TextDocument: lsp.TextDocumentIdentifier{URI: c.URL("pkg.go")},
+	})
+	reqs := s.Wait("textDocument/definition", 1)
+	if len(reqs) != 1 {
+		t.Fatalf("received %d definition requests; want 1", len(reqs))
+	}
+	cancel()
+	if err := result.WaitContext(ctx); err != context.Canceled {
+		t.Errorf("WaitContext() = %v; want %v", err, context.Canceled)
+	}
+
+	a := s.Wait("$/cancelRequest", 1)
+	if len(a) != 1 {
+		t.Fatalf("received %d cancel notifications; want 1", len(a))
+	}
+	var params lsp.CancelParams
+	if err := json.Unmarshal(a[0].Params, &params); err != nil {
+		t.Fatalf("can't unmarshal params: %v", err)
+	}
+	if params.ID != *reqs[0].ID {
+		t.Errorf("CancelParams.ID = %v; want %v", params.ID, *reqs[0].ID)
+	}
+
+	// late response for the canceled request must be ignored.
+	close(release)
+
+	s.HandleResult("textDocument/definition", json.RawMessage(`[{"uri":"file:///a.go"}]`))
+	result = c.GotoDefinition(&lsp.TextDocumentPositionParams{
+		TextDocument: lsp.TextDocumentIdentifier{URI: c.URL("pkg.go")},
+	})
+	if err := result.Wait(); err != nil {
+		t.Fatalf("Wait(): %v", err)
+	}
+	if n := len(result.Locations); n != 1 {
+		t.Errorf("len(Locations) = %d; want 1", n)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	c, s := newTestClient(t)
+	c.Handler = lsp.HandlerFunc(func(method string, params json.RawMessage) (interface{}, error) {
+		switch method {
+		case "workspace/configuration":
+			return []interface{}{nil}, nil
+		case "test/fail":
+			return nil, errors.New("failed")
+		}
+		return nil, &lsp.ResponseError{Code: lsp.ErrorCodeMethodNotFound, Message: method}
+	})
+
+	tests := []struct {
+		method string
+		result string
+		code   int
+	}{
+		{method: "workspace/configuration", result: `[null]`},
+		{method: "test/fail", code: lsp.ErrorCodeInternalError},
+		{method: "test/unknown", code: lsp.ErrorCodeMethodNotFound},
+	}
+	for _, tt := range tests {
+		var result json.RawMessage
+		err := s.Call(tt.method, struct{}{}, &result)
+		if tt.code != 0 {
+			var e *lsp.ResponseError
+			if !errors.As(err, &e) || e.Code != tt.code {
+				t.Errorf("%s: Call() = %v; want code %d", tt.method, err, tt.code)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: Call() = %v", tt.method, err)
+			continue
+		}
+		if s := string(result); s != tt.result {
+			t.Errorf("%s: Result = %s; want %s", tt.method, s, tt.result)
+		}
+	}
+}
+
+func TestHandlerNil(t *testing.T) {
+	_, s := newTestClient(t)
+	var e *lsp.ResponseError
+	err := s.Call("window/workDoneProgress/create", json.RawMessage(`{"token":"x"}`), nil)
+	if !errors.As(err, &e) || e.Code != lsp.ErrorCodeMethodNotFound {
+		t.Errorf("Call() = %v; want code %d", err, lsp.ErrorCodeMethodNotFound)
+	}
+}
+
+func TestClientConnectionLost(t *testing.T) {
+	c, s := newTestClient(t)
+	release := make(chan struct{})
+	defer close(release)
+	s.Handle("shutdown", blockHandler(release, nil))
+
+	result := c.Shutdown()
+	s.Wait("shutdown", 1)
+	select {
+	case <-c.Done():
+		t.Fatal("Done() is closed before the connection is lost")
+	default:
+	}
+	if err := c.Err(); err != nil {
+		t.Errorf("Err() = %v; want nil", err)
+	}
+	s.Disconnect()
+
+	if err := result.Wait(); err != io.EOF {
+		t.Errorf("Wait() = %v; want %v", err, io.EOF)
+	}
+	<-c.Done()
+	if err := c.Err(); err != io.EOF {
+		t.Errorf("Err() = %v; want %v", err, io.EOF)
+	}
+	if err := c.Shutdown().Wait(); err != io.EOF {
+		t.Errorf("Wait() after the connection is lost = %v; want %v", err, io.EOF)
+	}
+	if _, ok := <-c.Event; ok {
+		t.Errorf("Event is not closed")
+	}
+}
+
+func TestRecorder(t *testing.T) {
+	s := lsptest.NewServer()
+	defer s.Close()
+	s.HandleResult("textDocument/definition", json.RawMessage(`[]`))
+	var trace bytes.Buffer
+	c := lsp.NewClient(lsp.NewRecorder(s.Conn(), &trace))
+
+	result := c.GotoDefinition(&lsp.TextDocumentPositionParams{
+		TextDocument: lsp.TextDocumentIdentifier{URI: "file:///a.go"},
+	})
+	if err := result.Wait(); err != nil {
+		t.Fatalf("Wait(): %v", err)
+	}
+	c.Close()
+	<-c.Done()
+
+	entries, err := lsp.ReadTrace(&trace)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(entries); n != 2 {
+		t.Fatalf("len(entries) = %d; want 2", n)
+	}
+	want := []struct {
+		dir    string
+		method string
+	}{
+		{dir: lsp.TraceSend, method: "textDocument/definition"},
+		{dir: lsp.TraceRecv},
+	}
+	for i, e := range entries {
+		var msg lsp.Message
+		if err := json.Unmarshal(e.Message, &msg); err != nil {
+			t.Fatalf("can't unmarshal: %v", err)
+		}
+		if e.Dir != want[i].dir || msg.Method != want[i].method {
+			t.Errorf("entries[%d] = %s %q; want %s %q", i, e.Dir, msg.Method, want[i].dir, want[i].method)
+		}
+		if e.Time.IsZero() {
+			t.Errorf("entries[%d].Time is zero", i)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package lsp_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/lufia/acme-lsp/lsp"
+)
+
+func TestFormattingOptionsMarshalJSON(t *testing.T) {
+	tests := []struct {
+		opts lsp.FormattingOptions
+		want string
+	}{
+		// tabSize and insertSpaces are required even if they are zero.
+		{lsp.FormattingOptions{}, `{"tabSize":0,"insertSpaces":false}`},
+		{lsp.FormattingOptions{TabSize: 8}, `{"tabSize":8,"insertSpaces":false}`},
+		{
+			lsp.FormattingOptions{TabSize: 4, InsertSpaces: true, InsertFinalNewline: true},
+			`{"tabSize":4,"insertSpaces":true,"insertFinalNewline":true}`,
+		},
+	}
+	for _, tt := range tests {
+		b, err := json.Marshal(tt.opts)
+		if err != nil {
+			t.Errorf("Marshal(%+v): %v", tt.opts, err)
+			continue
+		}
+		if s := string(b); s != tt.want {
+			t.Errorf("Marshal(%+v) = %s; want %s", tt.opts, s, tt.want)
+		}
+	}
+}
+
+func TestFormatting(t *testing.T) {
+	c, s := newTestClient(t)
+	s.HandleResult("textDocument/formatting", json.RawMessage(`[
+		{"range":{"start":{"line":2,"character":0},"end":{"line":2,"character":4}},"newText":"\t"}
+	]`))
+	s.HandleResult("textDocument/rangeFormatting", json.RawMessage(`null`))
+	id := lsp.TextDocumentIdentifier{URI: c.URL("pkg.go")}
+	r := lsp.Range{
+		Start: lsp.Position{Line: 1, Character: 0},
+		End:   lsp.Position{Line: 3, Character: 0},
+	}
+	opts := lsp.FormattingOptions{TabSize: 4, InsertSpaces: true}
+	tests := []struct {
+		method string
+		call   func() *lsp.TextEditsResult
+		params interface{} // expected params; its type is used to decode the request
+		want   []lsp.TextEdit
+	}{
+		{
+			method: "textDocument/formatting",
+			call: func() *lsp.TextEditsResult {
+				return c.Formatting(&lsp.DocumentFormattingParams{TextDocument: id, Options: opts})
+			},
+			params: &lsp.DocumentFormattingParams{TextDocument: id, Options: opts},
+			want: []lsp.TextEdit{
+				{
+					Range: lsp.Range{
+						Start: lsp.Position{Line: 2, Character: 0},
+						End:   lsp.Position{Line: 2, Character: 4},
+					},
+					NewText: "\t",
+				},
+			},
+		},
+		{
+			method: "textDocument/rangeFormatting",
+			call: func() *lsp.TextEditsResult {
+				return c.RangeFormatting(&lsp.DocumentRangeFormattingParams{TextDocument: id, Range: r, Options: opts})
+			},
+			params: &lsp.DocumentRangeFormattingParams{TextDocument: id, Range: r, Options: opts},
+			want:   nil,
+		},
+	}
+	for _, tt := range tests {
+		result := tt.call()
+		if err := result.Wait(); err != nil {
+			t.Errorf("%s: %v", tt.method, err)
+			continue
+		}
+		if !reflect.DeepEqual(result.TextEdits, tt.want) {
+			t.Errorf("%s: TextEdits = %+v; want %+v", tt.method, result.TextEdits, tt.want)
+		}
+		a := s.Messages(tt.method)
+		if len(a) != 1 {
+			t.Errorf("received %d %s requests; want 1", len(a), tt.method)
+			continue
+		}
+		params := reflect.New(reflect.TypeOf(tt.params).Elem()).Interface()
+		if err := json.Unmarshal(a[0].Params, params); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(params, tt.params) {
+			t.Errorf("%s: params = %+v; want %+v", tt.method, params, tt.params)
+		}
+	}
+}
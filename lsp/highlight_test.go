@@ -0,0 +1,99 @@
+package lsp_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/lufia/acme-lsp/lsp"
+)
+
+func TestDocumentHighlightUnmarshalJSON(t *testing.T) {
+	r := lsp.Range{
+		Start: lsp.Position{Line: 1, Character: 4},
+		End:   lsp.Position{Line: 1, Character: 5},
+	}
+	tests := []struct {
+		data string
+		want lsp.DocumentHighlight
+	}{
+		{
+			`{"range":{"start":{"line":1,"character":4},"end":{"line":1,"character":5}},"kind":3}`,
+			lsp.DocumentHighlight{Range: r, Kind: lsp.DocumentHighlightKindWrite},
+		},
+		{
+			`{"range":{"start":{"line":1,"character":4},"end":{"line":1,"character":5}},"kind":2}`,
+			lsp.DocumentHighlight{Range: r, Kind: lsp.DocumentHighlightKindRead},
+		},
+		// the kind is text by default.
+		{
+			`{"range":{"start":{"line":1,"character":4},"end":{"line":1,"character":5}}}`,
+			lsp.DocumentHighlight{Range: r, Kind: lsp.DocumentHighlightKindText},
+		},
+		{
+			`{"range":{"start":{"line":1,"character":4},"end":{"line":1,"character":5}},"kind":null}`,
+			lsp.DocumentHighlight{Range: r, Kind: lsp.DocumentHighlightKindText},
+		},
+	}
+	for _, tt := range tests {
+		var h lsp.DocumentHighlight
+		if err := json.Unmarshal([]byte(tt.data), &h); err != nil {
+			t.Errorf("Unmarshal(%s): %v", tt.data, err)
+			continue
+		}
+		if h != tt.want {
+			t.Errorf("Unmarshal(%s) = %+v; want %+v", tt.data, h, tt.want)
+		}
+	}
+}
+
+func TestDocumentHighlightKindString(t *testing.T) {
+	tests := []struct {
+		kind lsp.DocumentHighlightKind
+		want string
+	}{
+		{lsp.DocumentHighlightKindText, "text"},
+		{lsp.DocumentHighlightKindRead, "read"},
+		{lsp.DocumentHighlightKindWrite, "write"},
+		{0, "kind(0)"},
+	}
+	for _, tt := range tests {
+		if s := tt.kind.String(); s != tt.want {
+			t.Errorf("DocumentHighlightKind(%d).String() = %q; want %q", int(tt.kind), s, tt.want)
+		}
+	}
+}
+
+func TestDocumentHighlight(t *testing.T) {
+	c, s := newTestClient(t)
+	s.HandleResult("textDocument/documentHighlight", json.RawMessage(`[
+		{"range":{"start":{"line":1,"character":4},"end":{"line":1,"character":5}},"kind":3},
+		{"range":{"start":{"line":2,"character":8},"end":{"line":2,"character":9}}}
+	]`))
+	params := &lsp.TextDocumentPositionParams{
+		TextDocument: lsp.TextDocumentIdentifier{URI: "file:///pkg.go"},
+		Position:     lsp.Position{Line: 1, Character: 4},
+	}
+	result := c.DocumentHighlight(params)
+	if err := result.Wait(); err != nil {
+		t.Fatalf("DocumentHighlight: %v", err)
+	}
+	kinds := make([]lsp.DocumentHighlightKind, len(result.Highlights))
+	for i, h := range result.Highlights {
+		kinds[i] = h.Kind
+	}
+	if want := []lsp.DocumentHighlightKind{lsp.DocumentHighlightKindWrite, lsp.DocumentHighlightKindText}; !reflect.DeepEqual(kinds, want) {
+		t.Errorf("kinds = %v; want %v", kinds, want)
+	}
+	a := s.Messages("textDocument/documentHighlight")
+	if len(a) != 1 {
+		t.Fatalf("received %d documentHighlight requests; want 1", len(a))
+	}
+	var p lsp.TextDocumentPositionParams
+	if err := json.Unmarshal(a[0].Params, &p); err != nil {
+		t.Fatal(err)
+	}
+	if p != *params {
+		t.Errorf("params = %+v; want %+v", p, *params)
+	}
+}
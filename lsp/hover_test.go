@@ -0,0 +1,145 @@
+package lsp_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/lufia/acme-lsp/lsp"
+)
+
+func TestMarkupContentUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		data string
+		want lsp.MarkupContent
+	}{
+		{`"doc"`, lsp.MarkupContent{Kind: lsp.MarkupKindPlainText, Value: "doc"}},
+		{`{"kind":"markdown","value":"*doc*"}`, lsp.MarkupContent{Kind: lsp.MarkupKindMarkdown, Value: "*doc*"}},
+		{`{"kind":"plaintext","value":""}`, lsp.MarkupContent{Kind: lsp.MarkupKindPlainText}},
+	}
+	for _, tt := range tests {
+		var m lsp.MarkupContent
+		if err := json.Unmarshal([]byte(tt.data), &m); err != nil {
+			t.Errorf("Unmarshal(%s): %v", tt.data, err)
+			continue
+		}
+		if m != tt.want {
+			t.Errorf("Unmarshal(%s) = %+v; want %+v", tt.data, m, tt.want)
+		}
+	}
+}
+
+func TestMarkedStringUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		data     string
+		want     lsp.MarkedString
+		markdown string
+	}{
+		{`"doc"`, lsp.MarkedString{Value: "doc"}, "doc"},
+		{`{"language":"go","value":"func F()"}`, lsp.MarkedString{Language: "go", Value: "func F()"}, "```go\nfunc F()\n```"},
+	}
+	for _, tt := range tests {
+		var s lsp.MarkedString
+		if err := json.Unmarshal([]byte(tt.data), &s); err != nil {
+			t.Errorf("Unmarshal(%s): %v", tt.data, err)
+			continue
+		}
+		if s != tt.want {
+			t.Errorf("Unmarshal(%s) = %+v; want %+v", tt.data, s, tt.want)
+		}
+		if m := s.Markdown(); m != tt.markdown {
+			t.Errorf("Markdown(%s) = %q; want %q", tt.data, m, tt.markdown)
+		}
+	}
+}
+
+func TestHoverUnmarshalJSON(t *testing.T) {
+	r := &lsp.Range{
+		Start: lsp.Position{Line: 1, Character: 2},
+		End:   lsp.Position{Line: 1, Character: 3},
+	}
+	tests := []struct {
+		name string
+		data string
+		want *lsp.Hover
+	}{
+		{
+			name: "MarkupContent",
+			data: `{"contents":{"kind":"plaintext","value":"func F()"}}`,
+			want: &lsp.Hover{
+				Contents: lsp.MarkupContent{Kind: lsp.MarkupKindPlainText, Value: "func F()"},
+			},
+		},
+		{
+			name: "string",
+			data: `{"contents":"doc","range":{"start":{"line":1,"character":2},"end":{"line":1,"character":3}}}`,
+			want: &lsp.Hover{
+				Contents: lsp.MarkupContent{Kind: lsp.MarkupKindMarkdown, Value: "doc"},
+				Range:    r,
+			},
+		},
+		{
+			name: "MarkedString",
+			data: `{"contents":{"language":"go","value":"func F()"}}`,
+			want: &lsp.Hover{
+				Contents: lsp.MarkupContent{Kind: lsp.MarkupKindMarkdown, Value: "```go\nfunc F()\n```"},
+			},
+		},
+		{
+			name: "MarkedString array",
+			data: `{"contents":[{"language":"go","value":"func F()"},"F does nothing."]}`,
+			want: &lsp.Hover{
+				Contents: lsp.MarkupContent{Kind: lsp.MarkupKindMarkdown, Value: "```go\nfunc F()\n```\n\nF does nothing."},
+			},
+		},
+		{
+			name: "empty array",
+			data: `{"contents":[]}`,
+			want: &lsp.Hover{
+				Contents: lsp.MarkupContent{Kind: lsp.MarkupKindMarkdown},
+			},
+		},
+		{
+			name: "null",
+			data: `null`,
+			want: nil,
+		},
+	}
+	for _, tt := range tests {
+		var h *lsp.Hover
+		if err := json.Unmarshal([]byte(tt.data), &h); err != nil {
+			t.Errorf("%s: Unmarshal: %v", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(h, tt.want) {
+			t.Errorf("%s: Unmarshal = %+v; want %+v", tt.name, h, tt.want)
+		}
+	}
+}
+
+func TestHover(t *testing.T) {
+	c, s := newTestClient(t)
+	s.HandleResult("textDocument/hover", json.RawMessage(`{"contents":"doc"}`))
+	params := &lsp.TextDocumentPositionParams{
+		TextDocument: lsp.TextDocumentIdentifier{URI: c.URL("pkg.go")},
+		Position:     lsp.Position{Line: 3, Character: 4},
+	}
+	r := c.Hover(params)
+	if err := r.Wait(); err != nil {
+		t.Fatalf("Hover: %v", err)
+	}
+	if r.Hover == nil || r.Hover.Contents.Value != "doc" {
+		t.Errorf("Hover = %+v; want doc", r.Hover)
+	}
+	a := s.Messages("textDocument/hover")
+	if len(a) != 1 {
+		t.Fatalf("received %d hover requests; want 1", len(a))
+	}
+	var p lsp.TextDocumentPositionParams
+	if err := json.Unmarshal(a[0].Params, &p); err != nil {
+		t.Fatal(err)
+	}
+	if p != *params {
+		t.Errorf("params = %+v; want %+v", p, *params)
+	}
+}
@@ -0,0 +1,72 @@
+package lsp_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/lufia/acme-lsp/lsp"
+)
+
+func TestLocations(t *testing.T) {
+	tests := []struct {
+		method string
+		call   func(c *lsp.Client, params *lsp.TextDocumentPositionParams) *lsp.LocationsResult
+	}{
+		{"textDocument/definition", (*lsp.Client).GotoDefinition},
+		{"textDocument/implementation", (*lsp.Client).Implementation},
+		{"textDocument/typeDefinition", (*lsp.Client).TypeDefinition},
+		{"textDocument/declaration", (*lsp.Client).Declaration},
+	}
+	want := []lsp.Location{
+		{
+			URI: "file:///pkg.go",
+			Range: lsp.Range{
+				Start: lsp.Position{Line: 3, Character: 5},
+				End:   lsp.Position{Line: 3, Character: 6},
+			},
+		},
+	}
+	c, s := newTestClient(t)
+	params := &lsp.TextDocumentPositionParams{
+		TextDocument: lsp.TextDocumentIdentifier{URI: "file:///a.go"},
+		Position:     lsp.Position{Line: 1, Character: 2},
+	}
+	for _, tt := range tests {
+		s.HandleResult(tt.method, want[0])
+		result := tt.call(c, params)
+		if err := result.Wait(); err != nil {
+			t.Errorf("%s: %v", tt.method, err)
+			continue
+		}
+		if !reflect.DeepEqual(result.Locations, want) {
+			t.Errorf("%s = %+v; want %+v", tt.method, result.Locations, want)
+		}
+		a := s.Messages(tt.method)
+		if len(a) != 1 {
+			t.Errorf("received %d %s requests; want 1", len(a), tt.method)
+			continue
+		}
+		var p lsp.TextDocumentPositionParams
+		if err := json.Unmarshal(a[0].Params, &p); err != nil {
+			t.Fatal(err)
+		}
+		if p != *params {
+			t.Errorf("%s: params = %+v; want %+v", tt.method, p, *params)
+		}
+	}
+}
+
+func TestLocationsNull(t *testing.T) {
+	c, s := newTestClient(t)
+	s.HandleResult("textDocument/implementation", json.RawMessage(`null`))
+	result := c.Implementation(&lsp.TextDocumentPositionParams{
+		TextDocument: lsp.TextDocumentIdentifier{URI: c.URL("pkg.go")},
+	})
+	if err := result.Wait(); err != nil {
+		t.Fatalf("Implementation: %v", err)
+	}
+	if len(result.Locations) != 0 {
+		t.Errorf("Locations = %+v; want empty", result.Locations)
+	}
+}
@@ -0,0 +1,289 @@
+// Package lsptest implements a fake language server for testing.
+package lsptest
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/lufia/acme-lsp/lsp"
+)
+
+// HandlerFunc responds to a request from the client.
+// If HandlerFunc returns *lsp.ResponseError, it is sent to the client as is.
+type HandlerFunc func(params json.RawMessage) (interface{}, error)
+
+// Server is a fake language server that communicates with the client over net.Pipe.
+// Requests from the client are responded by handlers registered with Handle;
+// the server responds MethodNotFound error to requests that have no handler.
+type Server struct {
+	conn net.Conn // server side
+	peer net.Conn // client side
+
+	wmu sync.Mutex // protects writes to conn
+
+	mu       sync.Mutex
+	cond     *sync.Cond
+	handlers map[string]HandlerFunc
+	received []*lsp.Message
+	calls    map[lsp.ID]chan *lsp.Message
+	lastID   int64
+	err      error
+}
+
+// NewServer starts a fake server.
+// The client should communicate with the server through s.Conn.
+func NewServer() *Server {
+	p, q := net.Pipe()
+	s := &Server{
+		conn:     p,
+		peer:     q,
+		handlers: make(map[string]HandlerFunc),
+		calls:    make(map[lsp.ID]chan *lsp.Message),
+	}
+	s.cond = sync.NewCond(&s.mu)
+	go s.run()
+	return s
+}
+
+// Conn returns a connection to the server for the client.
+func (s *Server) Conn() io.ReadWriteCloser {
+	return s.peer
+}
+
+// Handle registers fn as the handler for method.
+func (s *Server) Handle(method string, fn HandlerFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers[method] = fn
+}
+
+// HandleResult registers the handler that always responds result for method.
+func (s *Server) HandleResult(method string, result interface{}) {
+	s.Handle(method, func(params json.RawMessage) (interface{}, error) {
+		return result, nil
+	})
+}
+
+// Notify sends a notification to the client.
+func (s *Server) Notify(method string, params interface{}) error {
+	p, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	return s.write(&lsp.Message{
+		Version: "2.0",
+		Method:  method,
+		Params:  json.RawMessage(p),
+	})
+}
+
+// Call sends a request to the client, then waits for its response.
+// The result is stored into result if it is not nil.
+func (s *Server) Call(method string, params, result interface{}) error {
+	p, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.lastID++
+	id := lsp.StringID(fmt.Sprintf("lsptest-%d", s.lastID))
+	c := make(chan *lsp.Message, 1)
+	s.calls[id] = c
+	s.mu.Unlock()
+
+	err = s.write(&lsp.Message{
+		Version: "2.0",
+		ID:      &id,
+		Method:  method,
+		Params:  json.RawMessage(p),
+	})
+	if err != nil {
+		return err
+	}
+	msg, ok := <-c
+	if !ok {
+		return s.Err()
+	}
+	if msg.Error != nil {
+		return msg.Error
+	}
+	if result == nil {
+		return nil
+	}
+	return json.Unmarshal(msg.Result, result)
+}
+
+// Messages returns requests and notifications of method that the server received.
+func (s *Server) Messages(method string) []*lsp.Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.messages(method)
+}
+
+func (s *Server) messages(method string) []*lsp.Message {
+	var a []*lsp.Message
+	for _, msg := range s.received {
+		if msg.Method == method {
+			a = append(a, msg)
+		}
+	}
+	return a
+}
+
+// Wait waits until the server receives n messages of method, then returns them.
+// If the connection is closed before that, Wait returns the messages received.
+func (s *Server) Wait(method string, n int) []*lsp.Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for {
+		a := s.messages(method)
+		if len(a) >= n || s.err != nil {
+			return a
+		}
+		s.cond.Wait()
+	}
+}
+
+// Err returns the error that terminated the server.
+func (s *Server) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// Disconnect closes only the server side of the connection,
+// as if the server exited; the client will read io.EOF.
+func (s *Server) Disconnect() error {
+	return s.conn.Close()
+}
+
+// Close closes the connection.
+func (s *Server) Close() error {
+	s.peer.Close()
+	return s.conn.Close()
+}
+
+func (s *Server) run() {
+	r := bufio.NewReader(s.conn)
+	for {
+		msg, err := readMessage(r)
+		if err != nil {
+			s.mu.Lock()
+			s.err = err
+			for id, c := range s.calls {
+				close(c)
+				delete(s.calls, id)
+			}
+			s.cond.Broadcast()
+			s.mu.Unlock()
+			return
+		}
+		if msg.Method == "" { // response
+			s.mu.Lock()
+			if msg.ID != nil {
+				if c, ok := s.calls[*msg.ID]; ok {
+					c <- msg
+					delete(s.calls, *msg.ID)
+				}
+			}
+			s.mu.Unlock()
+			continue
+		}
+
+		s.mu.Lock()
+		s.received = append(s.received, msg)
+		fn := s.handlers[msg.Method]
+		s.cond.Broadcast()
+		s.mu.Unlock()
+		if msg.ID != nil {
+			go s.respond(msg, fn)
+		}
+	}
+}
+
+func (s *Server) respond(req *lsp.Message, fn HandlerFunc) {
+	resp := &lsp.Message{
+		Version: "2.0",
+		ID:      req.ID,
+	}
+	if fn == nil {
+		resp.Error = &lsp.ResponseError{
+			Code:    lsp.ErrorCodeMethodNotFound,
+			Message: "method not found: " + req.Method,
+		}
+		s.write(resp)
+		return
+	}
+	result, err := fn(req.Params)
+	if err != nil {
+		var e *lsp.ResponseError
+		if !errors.As(err, &e) {
+			e = &lsp.ResponseError{
+				Code:    lsp.ErrorCodeInternalError,
+				Message: err.Error(),
+			}
+		}
+		resp.Error = e
+		s.write(resp)
+		return
+	}
+	p, err := json.Marshal(result)
+	if err != nil {
+		resp.Error = &lsp.ResponseError{
+			Code:    lsp.ErrorCodeInternalError,
+			Message: err.Error(),
+		}
+	} else {
+		resp.Result = json.RawMessage(p)
+	}
+	s.write(resp)
+}
+
+func (s *Server) write(msg *lsp.Message) error {
+	p, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	s.wmu.Lock()
+	defer s.wmu.Unlock()
+	_, err = fmt.Fprintf(s.conn, "Content-Length: %d\r\n\r\n%s", len(p), p)
+	return err
+}
+
+func readMessage(r *bufio.Reader) (*lsp.Message, error) {
+	var contentLen int64
+	for {
+		s, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		s = strings.TrimSpace(s)
+		if s == "" {
+			break
+		}
+		a := strings.SplitN(s, ":", 2)
+		if len(a) < 2 {
+			continue
+		}
+		if strings.TrimSpace(a[0]) == "Content-Length" {
+			contentLen, _ = strconv.ParseInt(strings.TrimSpace(a[1]), 10, 64)
+		}
+	}
+	buf := bytes.NewBuffer(make([]byte, 0, contentLen))
+	if _, err := io.CopyN(buf, r, contentLen); err != nil {
+		return nil, err
+	}
+	var msg lsp.Message
+	if err := json.Unmarshal(buf.Bytes(), &msg); err != nil {
+		return nil, err
+	}
+	return &msg, nil
+}
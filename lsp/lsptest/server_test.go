@@ -0,0 +1,141 @@
+package lsptest
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/lufia/acme-lsp/lsp"
+)
+
+func newClient(t *testing.T) (*lsp.Client, *Server) {
+	t.Helper()
+	s := NewServer()
+	c := lsp.NewClient(s.Conn())
+	c.Debug = testing.Verbose()
+	t.Cleanup(func() {
+		c.Close()
+		s.Close()
+	})
+	return c, s
+}
+
+func TestServerInitialize(t *testing.T) {
+	c, s := newClient(t)
+	s.HandleResult("initialize", &lsp.InitializeResult{
+		Capabilities: lsp.ServerCapabilities{
+			HoverProvider: true,
+		},
+	})
+
+	result := c.Initialize(&lsp.InitializeParams{
+		RootURI: c.URL("."),
+	})
+	if err := result.Wait(); err != nil {
+		t.Fatalf("Initialize: %v", err)
+	}
+	if !result.Capabilities.HoverProvider {
+		t.Errorf("HoverProvider = false; want true")
+	}
+	if err := c.Initialized(&lsp.InitializedParams{}); err != nil {
+		t.Fatalf("Initialized: %v", err)
+	}
+	if a := s.Wait("initialized", 1); len(a) != 1 {
+		t.Errorf("received %d initialized notifications; want 1", len(a))
+	}
+
+	// shutdown has no handler.
+	var e *lsp.ResponseError
+	err := c.Shutdown().Wait()
+	if !errors.As(err, &e) || e.Code != lsp.ErrorCodeMethodNotFound {
+		t.Errorf("Shutdown: %v; want MethodNotFound error", err)
+	}
+}
+
+func TestServerDidChange(t *testing.T) {
+	c, s := newClient(t)
+
+	version := 2
+	want := lsp.DidChangeTextDocumentParams{
+		TextDocument: lsp.VersionedTextDocumentIdentifier{
+			TextDocumentIdentifier: lsp.TextDocumentIdentifier{
+				URI: c.URL("pkg.go"),
+			},
+			Version: &version,
+		},
+		ContentChanges: []lsp.TextDocumentContentChangeEvent{
+			{
+				Range: lsp.Range{
+					Start: lsp.Position{Line: 1, Character: 2},
+					End:   lsp.Position{Line: 1, Character: 4},
+				},
+				RangeLength: 2,
+				Text:        "xyz",
+			},
+		},
+	}
+	if err := c.DidChangeTextDocument(&want); err != nil {
+		t.Fatalf("DidChangeTextDocument: %v", err)
+	}
+	a := s.Wait("textDocument/didChange", 1)
+	if len(a) != 1 {
+		t.Fatalf("received %d didChange notifications; want 1", len(a))
+	}
+	var got lsp.DidChangeTextDocumentParams
+	if err := json.Unmarshal(a[0].Params, &got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("didChange = %+v; want %+v", got, want)
+	}
+}
+
+func TestServerNotify(t *testing.T) {
+	c, s := newClient(t)
+	params := lsp.PublishDiagnosticsParams{
+		URI: c.URL("pkg.go"),
+		Diagnostics: []lsp.Diagnostic{
+			{Message: "error"},
+		},
+	}
+	if err := s.Notify("textDocument/publishDiagnostics", &params); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+	select {
+	case msg := <-c.Event:
+		if msg.Method != "textDocument/publishDiagnostics" {
+			t.Errorf("Method = %q; want textDocument/publishDiagnostics", msg.Method)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out")
+	}
+}
+
+func TestServerCall(t *testing.T) {
+	c, s := newClient(t)
+	c.Handler = lsp.HandlerFunc(func(method string, params json.RawMessage) (interface{}, error) {
+		if method != "workspace/configuration" {
+			return nil, &lsp.ResponseError{Code: lsp.ErrorCodeMethodNotFound}
+		}
+		return []string{"ok"}, nil
+	})
+
+	var result []string
+	err := s.Call("workspace/configuration", &lsp.ConfigurationParams{
+		Items: []lsp.ConfigurationItem{{Section: "gopls"}},
+	}, &result)
+	if err != nil {
+		t.Fatalf("Call: %v", err)
+	}
+	if want := []string{"ok"}; !reflect.DeepEqual(result, want) {
+		t.Errorf("result = %v; want %v", result, want)
+	}
+
+	var e *lsp.ResponseError
+	err = s.Call("window/workDoneProgress/create", struct{}{}, nil)
+	if !errors.As(err, &e) || e.Code != lsp.ErrorCodeMethodNotFound {
+		t.Errorf("Call: %v; want MethodNotFound error", err)
+	}
+}
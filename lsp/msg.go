@@ -1,10 +1,13 @@
 package lsp
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"net/url"
 	"os"
 	"path"
+	"strings"
 )
 
 /*
@@ -106,9 +109,18 @@ type InitializeParams struct {
 
 // ClientCapabilities represents the interface described in the specification.
 type ClientCapabilities struct {
+	Workspace    WorkspaceClientCapabilities    `json:"workspace,omitempty"`
 	TextDocument TextDocumentClientCapabilities `json:"textDocument,omitempty"`
 }
 
+// WorkspaceClientCapabilities represents the interface described in the specification.
+type WorkspaceClientCapabilities struct {
+	ApplyEdit     bool `json:"applyEdit,omitempty"`
+	WorkspaceEdit struct {
+		DocumentChanges bool `json:"documentChanges,omitempty"`
+	} `json:"workspaceEdit,omitempty"`
+}
+
 // TextDocumentClientCapabilities represents the interface described in the specification.
 type TextDocumentClientCapabilities struct {
 	Declaration struct {
@@ -127,6 +139,39 @@ type TextDocumentClientCapabilities struct {
 		DynamicRegistration bool `json:"dynamicRegistration,omitempty"`
 		LinkSupport         bool `json:"linkSupport,omitempty"`
 	} `json:"implementation,omitempty"`
+	Hover struct {
+		DynamicRegistration bool     `json:"dynamicRegistration,omitempty"`
+		ContentFormat       []string `json:"contentFormat,omitempty"`
+	} `json:"hover,omitempty"`
+	Rename struct {
+		DynamicRegistration bool `json:"dynamicRegistration,omitempty"`
+		PrepareSupport      bool `json:"prepareSupport,omitempty"`
+	} `json:"rename,omitempty"`
+	DocumentSymbol struct {
+		DynamicRegistration               bool `json:"dynamicRegistration,omitempty"`
+		HierarchicalDocumentSymbolSupport bool `json:"hierarchicalDocumentSymbolSupport,omitempty"`
+	} `json:"documentSymbol,omitempty"`
+	CodeAction struct {
+		DynamicRegistration      bool                      `json:"dynamicRegistration,omitempty"`
+		CodeActionLiteralSupport *CodeActionLiteralSupport `json:"codeActionLiteralSupport,omitempty"`
+		IsPreferredSupport       bool                      `json:"isPreferredSupport,omitempty"`
+		DisabledSupport          bool                      `json:"disabledSupport,omitempty"`
+		DataSupport              bool                      `json:"dataSupport,omitempty"`
+		ResolveSupport           *ResolveSupport           `json:"resolveSupport,omitempty"`
+	} `json:"codeAction,omitempty"`
+}
+
+// CodeActionLiteralSupport represents the interface described in the specification.
+type CodeActionLiteralSupport struct {
+	CodeActionKind struct {
+		ValueSet []string `json:"valueSet"`
+	} `json:"codeActionKind"`
+}
+
+// ResolveSupport represents the interface described in the specification.
+// Properties are names of the properties that the client can resolve lazily.
+type ResolveSupport struct {
+	Properties []string `json:"properties"`
 }
 
 // InitializeResult represents the interface described in the specification.
@@ -143,10 +188,8 @@ type ServerCapabilities struct {
 	// TODO(lufia): missing
 	// typeDefinitionProvider
 	// implementationProvider
-	// codeActionProvider
 	// codeLensProvider
 	// documentOnTypeFormattingProvider
-	// renameProvider
 	// documentLinkProvider
 	// colorProvider
 	// foldingRangeProvider
@@ -166,6 +209,8 @@ type ServerCapabilities struct {
 	DocumentFormattingProvider      bool                    `json:"documentFormattingProvider,omitempty"`
 	DocumentRangeFormattingProvider bool                    `json:"documentRangeFormattingProvider,omitempty"`
 	ExecuteCommandProvider          ExecuteCommandOptions   `json:"executeCommandProvider,omitempty"`
+	RenameProvider                  RenameOptions           `json:"renameProvider,omitempty"`
+	CodeActionProvider              CodeActionOptions       `json:"codeActionProvider,omitempty"`
 }
 
 //"documentLinkProvider"
@@ -194,7 +239,55 @@ type CompletionOptions struct {
 
 // SignatureHelpOptions represents the interface described in the specification.
 type SignatureHelpOptions struct {
-	TriggerCharacters []string `json:"triggerCharacters"`
+	TriggerCharacters   []string `json:"triggerCharacters"`
+	RetriggerCharacters []string `json:"retriggerCharacters,omitempty"`
+}
+
+// RenameOptions represents the interface described in the specification.
+// The server can also respond a boolean instead of RenameOptions;
+// Enabled reports whether the server supports rename in any case.
+type RenameOptions struct {
+	Enabled         bool `json:"-"`
+	PrepareProvider bool `json:"prepareProvider,omitempty"`
+}
+
+// UnmarshalJSON implements json.Unmarshaler interface.
+func (o *RenameOptions) UnmarshalJSON(data []byte) error {
+	var b bool
+	if err := json.Unmarshal(data, &b); err == nil {
+		*o = RenameOptions{Enabled: b}
+		return nil
+	}
+	type renameOptions RenameOptions
+	if err := json.Unmarshal(data, (*renameOptions)(o)); err != nil {
+		return err
+	}
+	o.Enabled = true
+	return nil
+}
+
+// CodeActionOptions represents the interface described in the specification.
+// The server can also respond a boolean instead of CodeActionOptions;
+// Enabled reports whether the server supports code actions in any case.
+type CodeActionOptions struct {
+	Enabled         bool     `json:"-"`
+	CodeActionKinds []string `json:"codeActionKinds,omitempty"`
+	ResolveProvider bool     `json:"resolveProvider,omitempty"`
+}
+
+// UnmarshalJSON implements json.Unmarshaler interface.
+func (o *CodeActionOptions) UnmarshalJSON(data []byte) error {
+	var b bool
+	if err := json.Unmarshal(data, &b); err == nil {
+		*o = CodeActionOptions{Enabled: b}
+		return nil
+	}
+	type codeActionOptions CodeActionOptions
+	if err := json.Unmarshal(data, (*codeActionOptions)(o)); err != nil {
+		return err
+	}
+	o.Enabled = true
+	return nil
 }
 
 // ExecuteCommandOptions represents the interface described in the specification.
@@ -204,9 +297,6 @@ type ExecuteCommandOptions struct {
 
 // Initialize sends the initialize request to the server.
 func (c *Client) Initialize(params *InitializeParams) *InitializeResult {
-	// gopls don't support []LocationLink yet
-	params.Capabilities.TextDocument.Definition.LinkSupport = false
-
 	var result InitializeResult
 	result.c = c
 	result.call = c.Call("initialize", params, &result)
@@ -215,13 +305,24 @@ func (c *Client) Initialize(params *InitializeParams) *InitializeResult {
 
 // Wait waits for a response of initialize request.
 func (r *InitializeResult) Wait() error {
-	if err := r.c.Wait(r.call); err != nil {
+	return r.WaitContext(context.Background())
+}
+
+// WaitContext is like Wait but gives up waiting when ctx is done.
+func (r *InitializeResult) WaitContext(ctx context.Context) error {
+	if err := r.c.WaitContext(ctx, r.call); err != nil {
 		return err
 	}
 	r.c.cap = r.Capabilities
 	return nil
 }
 
+// Capabilities returns capabilities of the server.
+// It is valid after the initialize request is completed.
+func (c *Client) Capabilities() ServerCapabilities {
+	return c.cap
+}
+
 // InitializedParams represents the interface described in the specification.
 type InitializedParams struct {
 }
@@ -250,6 +351,11 @@ func (r *ShutdownResult) Wait() error {
 	return r.c.Wait(r.call)
 }
 
+// WaitContext is like Wait but gives up waiting when ctx is done.
+func (r *ShutdownResult) WaitContext(ctx context.Context) error {
+	return r.c.WaitContext(ctx, r.call)
+}
+
 // Exit sends the exit notification to the server.
 func (c *Client) Exit() error {
 	return c.Wait(c.Call("exit", nil, nil))
@@ -331,6 +437,11 @@ func (r *TextEditsResult) Wait() error {
 	return r.c.Wait(r.call)
 }
 
+// WaitContext is like Wait but gives up waiting when ctx is done.
+func (r *TextEditsResult) WaitContext(ctx context.Context) error {
+	return r.c.WaitContext(ctx, r.call)
+}
+
 // WillSaveWaitUntilTextDocument sends the document will save request to the server.
 func (c *Client) WillSaveWaitUntilTextDocument(params *WillSaveTextDocumentParams) *TextEditsResult {
 	var result TextEditsResult
@@ -380,7 +491,9 @@ type TextDocumentPositionParams struct {
 	Position     Position               `json:"position"`
 }
 
-// LocationsResult represents a result object for methods returning an array of Location.
+// LocationsResult represents a result object for methods returning locations.
+// The server can respond Location, Location[] or LocationLink[];
+// they are normalized into Locations.
 type LocationsResult struct {
 	Locations []Location
 
@@ -388,11 +501,46 @@ type LocationsResult struct {
 	call *Call
 }
 
+// locations decodes Location | Location[] | LocationLink[] | null into []Location.
+// LocationLink is converted to Location that points TargetSelectionRange,
+// or TargetRange if the former is empty.
+type locations []Location
+
+// UnmarshalJSON implements json.Unmarshaler interface.
+func (a *locations) UnmarshalJSON(data []byte) error {
+	type location struct {
+		Location
+		LocationLink
+	}
+	var v []location
+	if len(data) > 0 && data[0] == '{' {
+		v = make([]location, 1)
+		if err := json.Unmarshal(data, &v[0]); err != nil {
+			return err
+		}
+	} else if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*a = nil
+	for _, l := range v {
+		if l.TargetURI == "" {
+			*a = append(*a, l.Location)
+			continue
+		}
+		r := l.TargetSelectionRange
+		if r == (Range{}) {
+			r = l.TargetRange
+		}
+		*a = append(*a, Location{URI: l.TargetURI, Range: r})
+	}
+	return nil
+}
+
 // GotoDefinition sends the go to definition request to the server.
 func (c *Client) GotoDefinition(params *TextDocumentPositionParams) *LocationsResult {
 	var result LocationsResult
 	result.c = c
-	result.call = c.Call("textDocument/definition", params, &result.Locations)
+	result.call = c.Call("textDocument/definition", params, (*locations)(&result.Locations))
 	return &result
 }
 
@@ -401,6 +549,11 @@ func (r *LocationsResult) Wait() error {
 	return r.c.Wait(r.call)
 }
 
+// WaitContext is like Wait but gives up waiting when ctx is done.
+func (r *LocationsResult) WaitContext(ctx context.Context) error {
+	return r.c.WaitContext(ctx, r.call)
+}
+
 // ReferenceParams represents the interface described in the specification.
 type ReferenceParams struct {
 	TextDocumentPositionParams
@@ -412,10 +565,35 @@ type ReferenceContext struct {
 	IncludeDeclaration bool `json:"includeDeclaration"`
 }
 
+// References sends the find references request to the server.
 func (c *Client) References(params *ReferenceParams) *LocationsResult {
 	var result LocationsResult
 	result.c = c
-	result.call = c.Call("textDocument/references", params, &result.Locations)
+	result.call = c.Call("textDocument/references", params, (*locations)(&result.Locations))
+	return &result
+}
+
+// Implementation sends the go to implementation request to the server.
+func (c *Client) Implementation(params *TextDocumentPositionParams) *LocationsResult {
+	var result LocationsResult
+	result.c = c
+	result.call = c.Call("textDocument/implementation", params, (*locations)(&result.Locations))
+	return &result
+}
+
+// Declaration sends the go to declaration request to the server.
+func (c *Client) Declaration(params *TextDocumentPositionParams) *LocationsResult {
+	var result LocationsResult
+	result.c = c
+	result.call = c.Call("textDocument/declaration", params, (*locations)(&result.Locations))
+	return &result
+}
+
+// TypeDefinition sends the go to type definition request to the server.
+func (c *Client) TypeDefinition(params *TextDocumentPositionParams) *LocationsResult {
+	var result LocationsResult
+	result.c = c
+	result.call = c.Call("textDocument/typeDefinition", params, (*locations)(&result.Locations))
 	return &result
 }
 
@@ -452,6 +630,22 @@ func (r *DocumentLinksResult) Wait() error {
 	return r.c.Wait(r.call)
 }
 
+// WaitContext is like Wait but gives up waiting when ctx is done.
+func (r *DocumentLinksResult) WaitContext(ctx context.Context) error {
+	return r.c.WaitContext(ctx, r.call)
+}
+
+// ConfigurationParams represents the interface described in the specification.
+type ConfigurationParams struct {
+	Items []ConfigurationItem `json:"items"`
+}
+
+// ConfigurationItem represents the interface described in the specification.
+type ConfigurationItem struct {
+	ScopeURI DocumentURI `json:"scopeUri,omitempty"`
+	Section  string      `json:"section,omitempty"`
+}
+
 // PublishDiagnosticsParams represents the interface described in the specification.
 type PublishDiagnosticsParams struct {
 	URI         DocumentURI  `json:"uri"`
@@ -473,3 +667,1139 @@ type DiagnosticRelatedInformation struct {
 	Location Location `json:"location"`
 	Message  string   `json:"message"`
 }
+
+// MessageType represents types of the message.
+const (
+	MessageTypeError   = 1
+	MessageTypeWarning = 2
+	MessageTypeInfo    = 3
+	MessageTypeLog     = 4
+)
+
+// ShowMessageParams represents the interface described in the specification.
+type ShowMessageParams struct {
+	Type    int    `json:"type"`
+	Message string `json:"message"`
+}
+
+// LogMessageParams represents the interface described in the specification.
+type LogMessageParams struct {
+	Type    int    `json:"type"`
+	Message string `json:"message"`
+}
+
+// ProgressParams represents the interface described in the specification.
+// Token is either a number or a string, same as ID.
+type ProgressParams struct {
+	Token ID              `json:"token"`
+	Value json.RawMessage `json:"value"`
+}
+
+// WorkDoneProgress represents the union of WorkDoneProgressBegin,
+// WorkDoneProgressReport and WorkDoneProgressEnd in the specification.
+// It is a value of ProgressParams for work done progress.
+type WorkDoneProgress struct {
+	Kind        string `json:"kind"` // begin, report, end
+	Title       string `json:"title,omitempty"`
+	Cancellable bool   `json:"cancellable,omitempty"`
+	Message     string `json:"message,omitempty"`
+	Percentage  int    `json:"percentage,omitempty"`
+}
+
+// MarkupKind represents kinds of MarkupContent.
+const (
+	MarkupKindPlainText = "plaintext"
+	MarkupKindMarkdown  = "markdown"
+)
+
+// MarkupContent represents the interface described in the specification.
+type MarkupContent struct {
+	Kind  string `json:"kind"`
+	Value string `json:"value"`
+}
+
+// UnmarshalJSON implements json.Unmarshaler interface.
+// It accepts a string as plain text, in addition to an object,
+// because documentation of some interfaces is string | MarkupContent.
+func (m *MarkupContent) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		m.Kind = MarkupKindPlainText
+		m.Value = s
+		return nil
+	}
+	type markupContent MarkupContent
+	return json.Unmarshal(data, (*markupContent)(m))
+}
+
+// MarkedString represents the interface described in the specification.
+// It is deprecated in the specification but some servers still use it.
+type MarkedString struct {
+	Language string `json:"language"`
+	Value    string `json:"value"`
+}
+
+// UnmarshalJSON implements json.Unmarshaler interface.
+// It accepts either a string or an object.
+func (s *MarkedString) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &s.Value); err == nil {
+		s.Language = ""
+		return nil
+	}
+	type markedString MarkedString
+	return json.Unmarshal(data, (*markedString)(s))
+}
+
+// Markdown returns a markdown representation of s.
+func (s MarkedString) Markdown() string {
+	if s.Language == "" {
+		return s.Value
+	}
+	return "```" + s.Language + "\n" + s.Value + "\n```"
+}
+
+// Hover represents the interface described in the specification.
+// Contents is normalized to MarkupContent even if the server
+// responded MarkedString or an array of MarkedString.
+type Hover struct {
+	Contents MarkupContent `json:"contents"`
+	Range    *Range        `json:"range,omitempty"`
+}
+
+// UnmarshalJSON implements json.Unmarshaler interface.
+func (h *Hover) UnmarshalJSON(data []byte) error {
+	var v struct {
+		Contents json.RawMessage `json:"contents"`
+		Range    *Range          `json:"range"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	h.Range = v.Range
+	return h.Contents.unmarshalHover(v.Contents)
+}
+
+// unmarshalHover decodes MarkupContent | MarkedString | MarkedString[] into m.
+func (m *MarkupContent) unmarshalHover(data []byte) error {
+	var a []MarkedString
+	if err := json.Unmarshal(data, &a); err == nil {
+		s := make([]string, len(a))
+		for i, v := range a {
+			s[i] = v.Markdown()
+		}
+		m.Kind = MarkupKindMarkdown
+		m.Value = strings.Join(s, "\n\n")
+		return nil
+	}
+	var c struct {
+		Kind *string `json:"kind"`
+	}
+	if err := json.Unmarshal(data, &c); err == nil && c.Kind != nil {
+		type markupContent MarkupContent
+		return json.Unmarshal(data, (*markupContent)(m))
+	}
+	var s MarkedString
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	m.Kind = MarkupKindMarkdown
+	m.Value = s.Markdown()
+	return nil
+}
+
+// HoverResult represents a result object for hover request.
+// Hover is nil if the server has no information.
+type HoverResult struct {
+	Hover *Hover
+
+	c    *Client
+	call *Call
+}
+
+// Hover sends the hover request to the server.
+func (c *Client) Hover(params *TextDocumentPositionParams) *HoverResult {
+	var result HoverResult
+	result.c = c
+	result.call = c.Call("textDocument/hover", params, &result.Hover)
+	return &result
+}
+
+// Wait waits for a response of hover request.
+func (r *HoverResult) Wait() error {
+	return r.c.Wait(r.call)
+}
+
+// WaitContext is like Wait but gives up waiting when ctx is done.
+func (r *HoverResult) WaitContext(ctx context.Context) error {
+	return r.c.WaitContext(ctx, r.call)
+}
+
+// CompletionTriggerKind represents how a completion was triggered.
+const (
+	CompletionTriggerKindInvoked                         = 1
+	CompletionTriggerKindTriggerCharacter                = 2
+	CompletionTriggerKindTriggerForIncompleteCompletions = 3
+)
+
+// CompletionParams represents the interface described in the specification.
+type CompletionParams struct {
+	TextDocumentPositionParams
+	Context *CompletionContext `json:"context,omitempty"`
+}
+
+// CompletionContext represents the interface described in the specification.
+type CompletionContext struct {
+	TriggerKind      int    `json:"triggerKind"`
+	TriggerCharacter string `json:"triggerCharacter,omitempty"`
+}
+
+// InsertTextFormat represents formats of CompletionItem.InsertText.
+const (
+	InsertTextFormatPlainText = 1
+	InsertTextFormatSnippet   = 2
+)
+
+// CompletionItem represents the interface described in the specification.
+type CompletionItem struct {
+	Label               string          `json:"label"`
+	Kind                int             `json:"kind,omitempty"`
+	Detail              string          `json:"detail,omitempty"`
+	Documentation       *MarkupContent  `json:"documentation,omitempty"`
+	SortText            string          `json:"sortText,omitempty"`
+	FilterText          string          `json:"filterText,omitempty"`
+	InsertText          string          `json:"insertText,omitempty"`
+	InsertTextFormat    int             `json:"insertTextFormat,omitempty"`
+	TextEdit            *TextEdit       `json:"textEdit,omitempty"`
+	AdditionalTextEdits []TextEdit      `json:"additionalTextEdits,omitempty"`
+	Command             *Command        `json:"command,omitempty"`
+	Data                json.RawMessage `json:"data,omitempty"`
+}
+
+// Command represents the interface described in the specification.
+type Command struct {
+	Title     string            `json:"title"`
+	Command   string            `json:"command"`
+	Arguments []json.RawMessage `json:"arguments,omitempty"`
+}
+
+// CompletionList represents the interface described in the specification.
+type CompletionList struct {
+	IsIncomplete bool             `json:"isIncomplete"`
+	Items        []CompletionItem `json:"items"`
+}
+
+// UnmarshalJSON implements json.Unmarshaler interface.
+// It accepts an array of CompletionItem as a complete list, in addition to CompletionList.
+func (l *CompletionList) UnmarshalJSON(data []byte) error {
+	var items []CompletionItem
+	if err := json.Unmarshal(data, &items); err == nil {
+		l.IsIncomplete = false
+		l.Items = items
+		return nil
+	}
+	type completionList CompletionList
+	return json.Unmarshal(data, (*completionList)(l))
+}
+
+// CompletionResult represents a result object for completion request.
+type CompletionResult struct {
+	List CompletionList
+
+	c    *Client
+	call *Call
+}
+
+// Completion sends the completion request to the server.
+func (c *Client) Completion(params *CompletionParams) *CompletionResult {
+	var result CompletionResult
+	result.c = c
+	result.call = c.Call("textDocument/completion", params, &result.List)
+	return &result
+}
+
+// Wait waits for a response of completion request.
+func (r *CompletionResult) Wait() error {
+	return r.c.Wait(r.call)
+}
+
+// WaitContext is like Wait but gives up waiting when ctx is done.
+func (r *CompletionResult) WaitContext(ctx context.Context) error {
+	return r.c.WaitContext(ctx, r.call)
+}
+
+// CompletionItemResult represents a result object for completion item resolve request.
+type CompletionItemResult struct {
+	Item CompletionItem
+
+	c    *Client
+	call *Call
+}
+
+// ResolveCompletionItem sends the completion item resolve request to the server.
+func (c *Client) ResolveCompletionItem(item *CompletionItem) *CompletionItemResult {
+	var result CompletionItemResult
+	result.c = c
+	result.call = c.Call("completionItem/resolve", item, &result.Item)
+	return &result
+}
+
+// Wait waits for a response of completion item resolve request.
+func (r *CompletionItemResult) Wait() error {
+	return r.c.Wait(r.call)
+}
+
+// WaitContext is like Wait but gives up waiting when ctx is done.
+func (r *CompletionItemResult) WaitContext(ctx context.Context) error {
+	return r.c.WaitContext(ctx, r.call)
+}
+
+// SignatureHelpTriggerKind represents how a signature help was triggered.
+const (
+	SignatureHelpTriggerKindInvoked          = 1
+	SignatureHelpTriggerKindTriggerCharacter = 2
+	SignatureHelpTriggerKindContentChange    = 3
+)
+
+// SignatureHelpParams represents the interface described in the specification.
+type SignatureHelpParams struct {
+	TextDocumentPositionParams
+	Context *SignatureHelpContext `json:"context,omitempty"`
+}
+
+// SignatureHelpContext represents the interface described in the specification.
+type SignatureHelpContext struct {
+	TriggerKind         int            `json:"triggerKind"`
+	TriggerCharacter    string         `json:"triggerCharacter,omitempty"`
+	IsRetrigger         bool           `json:"isRetrigger"`
+	ActiveSignatureHelp *SignatureHelp `json:"activeSignatureHelp,omitempty"`
+}
+
+// SignatureHelp represents the interface described in the specification.
+type SignatureHelp struct {
+	Signatures      []SignatureInformation `json:"signatures"`
+	ActiveSignature int                    `json:"activeSignature,omitempty"`
+	ActiveParameter int                    `json:"activeParameter,omitempty"`
+}
+
+// SignatureInformation represents the interface described in the specification.
+type SignatureInformation struct {
+	Label           string                 `json:"label"`
+	Documentation   *MarkupContent         `json:"documentation,omitempty"`
+	Parameters      []ParameterInformation `json:"parameters,omitempty"`
+	ActiveParameter *int                   `json:"activeParameter,omitempty"`
+}
+
+// ParameterInformation represents the interface described in the specification.
+type ParameterInformation struct {
+	Label         ParameterLabel `json:"label"`
+	Documentation *MarkupContent `json:"documentation,omitempty"`
+}
+
+// ParameterLabel represents a label of ParameterInformation.
+// It is either a substring of the signature label,
+// or start and end offsets within the signature label.
+type ParameterLabel struct {
+	Text    string
+	Offsets *[2]int
+}
+
+// MarshalJSON implements json.Marshaler interface.
+func (l ParameterLabel) MarshalJSON() ([]byte, error) {
+	if l.Offsets != nil {
+		return json.Marshal(l.Offsets)
+	}
+	return json.Marshal(l.Text)
+}
+
+// UnmarshalJSON implements json.Unmarshaler interface.
+func (l *ParameterLabel) UnmarshalJSON(data []byte) error {
+	var offsets [2]int
+	if err := json.Unmarshal(data, &offsets); err == nil {
+		l.Text = ""
+		l.Offsets = &offsets
+		return nil
+	}
+	l.Offsets = nil
+	return json.Unmarshal(data, &l.Text)
+}
+
+// Range returns offsets in runes of l within the signature label s.
+// If l is not found in s, ok is false.
+func (l ParameterLabel) Range(s string) (start, end int, ok bool) {
+	if l.Offsets != nil {
+		start, end = l.Offsets[0], l.Offsets[1]
+		if start < 0 || start > end || end > len([]rune(s)) {
+			return 0, 0, false
+		}
+		return start, end, true
+	}
+	i := strings.Index(s, l.Text)
+	if i < 0 || l.Text == "" {
+		return 0, 0, false
+	}
+	start = len([]rune(s[:i]))
+	return start, start + len([]rune(l.Text)), true
+}
+
+// SignatureHelpResult represents a result object for signature help request.
+// Help is nil if the server has no information.
+type SignatureHelpResult struct {
+	Help *SignatureHelp
+
+	c    *Client
+	call *Call
+}
+
+// SignatureHelp sends the signature help request to the server.
+func (c *Client) SignatureHelp(params *SignatureHelpParams) *SignatureHelpResult {
+	var result SignatureHelpResult
+	result.c = c
+	result.call = c.Call("textDocument/signatureHelp", params, &result.Help)
+	return &result
+}
+
+// Wait waits for a response of signature help request.
+func (r *SignatureHelpResult) Wait() error {
+	return r.c.Wait(r.call)
+}
+
+// WaitContext is like Wait but gives up waiting when ctx is done.
+func (r *SignatureHelpResult) WaitContext(ctx context.Context) error {
+	return r.c.WaitContext(ctx, r.call)
+}
+
+// WorkspaceEdit represents the interface described in the specification.
+// The client don't support resource operations such as create, rename or delete files,
+// therefore DocumentChanges contains only TextDocumentEdit.
+type WorkspaceEdit struct {
+	Changes         map[DocumentURI][]TextEdit `json:"changes,omitempty"`
+	DocumentChanges []TextDocumentEdit         `json:"documentChanges,omitempty"`
+}
+
+// Edits returns all edits in e for each document.
+func (e *WorkspaceEdit) Edits() map[DocumentURI][]TextEdit {
+	m := make(map[DocumentURI][]TextEdit)
+	for uri, edits := range e.Changes {
+		m[uri] = append(m[uri], edits...)
+	}
+	for _, c := range e.DocumentChanges {
+		uri := c.TextDocument.URI
+		m[uri] = append(m[uri], c.Edits...)
+	}
+	return m
+}
+
+// TextDocumentEdit represents the interface described in the specification.
+type TextDocumentEdit struct {
+	TextDocument VersionedTextDocumentIdentifier `json:"textDocument"`
+	Edits        []TextEdit                      `json:"edits"`
+}
+
+// WorkspaceEditResult represents a result object for methods returning WorkspaceEdit.
+// Edit is nil if the server has no changes.
+type WorkspaceEditResult struct {
+	Edit *WorkspaceEdit
+
+	c    *Client
+	call *Call
+}
+
+// Wait waits for a response of any request.
+func (r *WorkspaceEditResult) Wait() error {
+	return r.c.Wait(r.call)
+}
+
+// WaitContext is like Wait but gives up waiting when ctx is done.
+func (r *WorkspaceEditResult) WaitContext(ctx context.Context) error {
+	return r.c.WaitContext(ctx, r.call)
+}
+
+// RenameRange represents a result of prepare rename request.
+// The server responds either a Range, a Range with Placeholder,
+// or DefaultBehavior that means the client should use its own rule.
+type RenameRange struct {
+	Range           Range  `json:"range"`
+	Placeholder     string `json:"placeholder,omitempty"`
+	DefaultBehavior bool   `json:"defaultBehavior,omitempty"`
+}
+
+// UnmarshalJSON implements json.Unmarshaler interface.
+func (r *RenameRange) UnmarshalJSON(data []byte) error {
+	var v struct {
+		Range
+		InnerRange      *Range `json:"range"`
+		Placeholder     string `json:"placeholder"`
+		DefaultBehavior bool   `json:"defaultBehavior"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*r = RenameRange{
+		Range:           v.Range,
+		Placeholder:     v.Placeholder,
+		DefaultBehavior: v.DefaultBehavior,
+	}
+	if v.InnerRange != nil {
+		r.Range = *v.InnerRange
+	}
+	return nil
+}
+
+// PrepareRenameResult represents a result object for prepare rename request.
+// Range is nil if the position is not valid to rename.
+type PrepareRenameResult struct {
+	Range *RenameRange
+
+	c    *Client
+	call *Call
+}
+
+// PrepareRename sends the prepare rename request to the server.
+func (c *Client) PrepareRename(params *TextDocumentPositionParams) *PrepareRenameResult {
+	var result PrepareRenameResult
+	result.c = c
+	result.call = c.Call("textDocument/prepareRename", params, &result.Range)
+	return &result
+}
+
+// Wait waits for a response of prepare rename request.
+func (r *PrepareRenameResult) Wait() error {
+	return r.c.Wait(r.call)
+}
+
+// WaitContext is like Wait but gives up waiting when ctx is done.
+func (r *PrepareRenameResult) WaitContext(ctx context.Context) error {
+	return r.c.WaitContext(ctx, r.call)
+}
+
+// RenameParams represents the interface described in the specification.
+type RenameParams struct {
+	TextDocumentPositionParams
+	NewName string `json:"newName"`
+}
+
+// Rename sends the rename request to the server.
+func (c *Client) Rename(params *RenameParams) *WorkspaceEditResult {
+	var result WorkspaceEditResult
+	result.c = c
+	result.call = c.Call("textDocument/rename", params, &result.Edit)
+	return &result
+}
+
+// ApplyWorkspaceEditParams represents the interface described in the specification.
+type ApplyWorkspaceEditParams struct {
+	Label string        `json:"label,omitempty"`
+	Edit  WorkspaceEdit `json:"edit"`
+}
+
+// ApplyWorkspaceEditResponse represents the interface described in the specification.
+type ApplyWorkspaceEditResponse struct {
+	Applied       bool   `json:"applied"`
+	FailureReason string `json:"failureReason,omitempty"`
+}
+
+// FormattingOptions represents the interface described in the specification.
+type FormattingOptions struct {
+	TabSize                int  `json:"tabSize"`
+	InsertSpaces           bool `json:"insertSpaces"`
+	TrimTrailingWhitespace bool `json:"trimTrailingWhitespace,omitempty"`
+	InsertFinalNewline     bool `json:"insertFinalNewline,omitempty"`
+	TrimFinalNewlines      bool `json:"trimFinalNewlines,omitempty"`
+}
+
+// DocumentFormattingParams represents the interface described in the specification.
+type DocumentFormattingParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+	Options      FormattingOptions      `json:"options"`
+}
+
+// Formatting sends the document formatting request to the server.
+func (c *Client) Formatting(params *DocumentFormattingParams) *TextEditsResult {
+	var result TextEditsResult
+	result.c = c
+	result.call = c.Call("textDocument/formatting", params, &result.TextEdits)
+	return &result
+}
+
+// DocumentRangeFormattingParams represents the interface described in the specification.
+type DocumentRangeFormattingParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+	Range        Range                  `json:"range"`
+	Options      FormattingOptions      `json:"options"`
+}
+
+// RangeFormatting sends the document range formatting request to the server.
+func (c *Client) RangeFormatting(params *DocumentRangeFormattingParams) *TextEditsResult {
+	var result TextEditsResult
+	result.c = c
+	result.call = c.Call("textDocument/rangeFormatting", params, &result.TextEdits)
+	return &result
+}
+
+// CodeActionKind represents kinds of code actions.
+const (
+	CodeActionKindQuickFix              = "quickfix"
+	CodeActionKindRefactor              = "refactor"
+	CodeActionKindRefactorExtract       = "refactor.extract"
+	CodeActionKindRefactorInline        = "refactor.inline"
+	CodeActionKindRefactorRewrite       = "refactor.rewrite"
+	CodeActionKindSource                = "source"
+	CodeActionKindSourceOrganizeImports = "source.organizeImports"
+)
+
+// CodeActionParams represents the interface described in the specification.
+type CodeActionParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+	Range        Range                  `json:"range"`
+	Context      CodeActionContext      `json:"context"`
+}
+
+// CodeActionContext represents the interface described in the specification.
+type CodeActionContext struct {
+	Diagnostics []Diagnostic `json:"diagnostics"`
+	Only        []string     `json:"only,omitempty"`
+}
+
+// CodeAction represents the interface described in the specification.
+// The server can also respond a Command instead of CodeAction;
+// then Title and Command are set.
+type CodeAction struct {
+	Title       string       `json:"title"`
+	Kind        string       `json:"kind,omitempty"`
+	Diagnostics []Diagnostic `json:"diagnostics,omitempty"`
+	IsPreferred bool         `json:"isPreferred,omitempty"`
+	Disabled    *struct {
+		Reason string `json:"reason"`
+	} `json:"disabled,omitempty"`
+	Edit    *WorkspaceEdit  `json:"edit,omitempty"`
+	Command *Command        `json:"command,omitempty"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+// UnmarshalJSON implements json.Unmarshaler interface.
+func (a *CodeAction) UnmarshalJSON(data []byte) error {
+	var v struct {
+		Command json.RawMessage `json:"command"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	if len(v.Command) > 0 && v.Command[0] == '"' {
+		var cmd Command
+		if err := json.Unmarshal(data, &cmd); err != nil {
+			return err
+		}
+		*a = CodeAction{Title: cmd.Title, Command: &cmd}
+		return nil
+	}
+	type codeAction CodeAction
+	*a = CodeAction{}
+	return json.Unmarshal(data, (*codeAction)(a))
+}
+
+// CodeActionResult represents a result object for code action request.
+type CodeActionResult struct {
+	Actions []CodeAction
+
+	c    *Client
+	call *Call
+}
+
+// CodeAction sends the code action request to the server.
+func (c *Client) CodeAction(params *CodeActionParams) *CodeActionResult {
+	var result CodeActionResult
+	result.c = c
+	result.call = c.Call("textDocument/codeAction", params, &result.Actions)
+	return &result
+}
+
+// Wait waits for a response of code action request.
+func (r *CodeActionResult) Wait() error {
+	return r.c.Wait(r.call)
+}
+
+// WaitContext is like Wait but gives up waiting when ctx is done.
+func (r *CodeActionResult) WaitContext(ctx context.Context) error {
+	return r.c.WaitContext(ctx, r.call)
+}
+
+// CodeActionItemResult represents a result object for code action resolve request.
+type CodeActionItemResult struct {
+	Action CodeAction
+
+	c    *Client
+	call *Call
+}
+
+// ResolveCodeAction sends the code action resolve request to the server.
+func (c *Client) ResolveCodeAction(action *CodeAction) *CodeActionItemResult {
+	var result CodeActionItemResult
+	result.c = c
+	result.call = c.Call("codeAction/resolve", action, &result.Action)
+	return &result
+}
+
+// Wait waits for a response of code action resolve request.
+func (r *CodeActionItemResult) Wait() error {
+	return r.c.Wait(r.call)
+}
+
+// WaitContext is like Wait but gives up waiting when ctx is done.
+func (r *CodeActionItemResult) WaitContext(ctx context.Context) error {
+	return r.c.WaitContext(ctx, r.call)
+}
+
+// ExecuteCommandParams represents the interface described in the specification.
+type ExecuteCommandParams struct {
+	Command   string            `json:"command"`
+	Arguments []json.RawMessage `json:"arguments,omitempty"`
+}
+
+// ExecuteCommandResult represents a result object for execute command request.
+type ExecuteCommandResult struct {
+	Result json.RawMessage
+
+	c    *Client
+	call *Call
+}
+
+// ExecuteCommand sends the execute command request to the server.
+func (c *Client) ExecuteCommand(params *ExecuteCommandParams) *ExecuteCommandResult {
+	var result ExecuteCommandResult
+	result.c = c
+	result.call = c.Call("workspace/executeCommand", params, &result.Result)
+	return &result
+}
+
+// Wait waits for a response of execute command request.
+func (r *ExecuteCommandResult) Wait() error {
+	return r.c.Wait(r.call)
+}
+
+// WaitContext is like Wait but gives up waiting when ctx is done.
+func (r *ExecuteCommandResult) WaitContext(ctx context.Context) error {
+	return r.c.WaitContext(ctx, r.call)
+}
+
+// SymbolKind represents kinds of symbols.
+type SymbolKind int
+
+// SymbolKind values defined in the specification.
+const (
+	SymbolKindFile          SymbolKind = 1
+	SymbolKindModule        SymbolKind = 2
+	SymbolKindNamespace     SymbolKind = 3
+	SymbolKindPackage       SymbolKind = 4
+	SymbolKindClass         SymbolKind = 5
+	SymbolKindMethod        SymbolKind = 6
+	SymbolKindProperty      SymbolKind = 7
+	SymbolKindField         SymbolKind = 8
+	SymbolKindConstructor   SymbolKind = 9
+	SymbolKindEnum          SymbolKind = 10
+	SymbolKindInterface     SymbolKind = 11
+	SymbolKindFunction      SymbolKind = 12
+	SymbolKindVariable      SymbolKind = 13
+	SymbolKindConstant      SymbolKind = 14
+	SymbolKindString        SymbolKind = 15
+	SymbolKindNumber        SymbolKind = 16
+	SymbolKindBoolean       SymbolKind = 17
+	SymbolKindArray         SymbolKind = 18
+	SymbolKindObject        SymbolKind = 19
+	SymbolKindKey           SymbolKind = 20
+	SymbolKindNull          SymbolKind = 21
+	SymbolKindEnumMember    SymbolKind = 22
+	SymbolKindStruct        SymbolKind = 23
+	SymbolKindEvent         SymbolKind = 24
+	SymbolKindOperator      SymbolKind = 25
+	SymbolKindTypeParameter SymbolKind = 26
+)
+
+var symbolKindNames = [...]string{
+	SymbolKindFile:          "file",
+	SymbolKindModule:        "module",
+	SymbolKindNamespace:     "namespace",
+	SymbolKindPackage:       "package",
+	SymbolKindClass:         "class",
+	SymbolKindMethod:        "method",
+	SymbolKindProperty:      "property",
+	SymbolKindField:         "field",
+	SymbolKindConstructor:   "constructor",
+	SymbolKindEnum:          "enum",
+	SymbolKindInterface:     "interface",
+	SymbolKindFunction:      "func",
+	SymbolKindVariable:      "var",
+	SymbolKindConstant:      "const",
+	SymbolKindString:        "string",
+	SymbolKindNumber:        "number",
+	SymbolKindBoolean:       "bool",
+	SymbolKindArray:         "array",
+	SymbolKindObject:        "object",
+	SymbolKindKey:           "key",
+	SymbolKindNull:          "null",
+	SymbolKindEnumMember:    "enummember",
+	SymbolKindStruct:        "struct",
+	SymbolKindEvent:         "event",
+	SymbolKindOperator:      "operator",
+	SymbolKindTypeParameter: "typeparam",
+}
+
+// String returns a short name of k.
+func (k SymbolKind) String() string {
+	if k > 0 && int(k) < len(symbolKindNames) {
+		return symbolKindNames[k]
+	}
+	return fmt.Sprintf("kind(%d)", int(k))
+}
+
+// DocumentSymbolParams represents the interface described in the specification.
+type DocumentSymbolParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+}
+
+// DocumentSymbol represents the interface described in the specification.
+type DocumentSymbol struct {
+	Name           string           `json:"name"`
+	Detail         string           `json:"detail,omitempty"`
+	Kind           SymbolKind       `json:"kind"`
+	Deprecated     bool             `json:"deprecated,omitempty"`
+	Range          Range            `json:"range"`
+	SelectionRange Range            `json:"selectionRange"`
+	Children       []DocumentSymbol `json:"children,omitempty"`
+}
+
+// SymbolInformation represents the interface described in the specification.
+type SymbolInformation struct {
+	Name          string     `json:"name"`
+	Kind          SymbolKind `json:"kind"`
+	Deprecated    bool       `json:"deprecated,omitempty"`
+	Location      Location   `json:"location"`
+	ContainerName string     `json:"containerName,omitempty"`
+}
+
+// DocumentSymbolResult represents a result object for document symbol request.
+// The server responds either hierarchical symbols or flat symbols;
+// the former are stored in Symbols, the latter in Information.
+type DocumentSymbolResult struct {
+	Symbols     []DocumentSymbol
+	Information []SymbolInformation
+
+	c    *Client
+	call *Call
+}
+
+// DocumentSymbol sends the document symbol request to the server.
+func (c *Client) DocumentSymbol(params *DocumentSymbolParams) *DocumentSymbolResult {
+	var result DocumentSymbolResult
+	result.c = c
+	result.call = c.Call("textDocument/documentSymbol", params, (*documentSymbols)(&result))
+	return &result
+}
+
+// documentSymbols decodes a response of document symbol request into DocumentSymbolResult.
+type documentSymbols DocumentSymbolResult
+
+// UnmarshalJSON implements json.Unmarshaler interface.
+func (r *documentSymbols) UnmarshalJSON(data []byte) error {
+	var a []json.RawMessage
+	if err := json.Unmarshal(data, &a); err != nil {
+		return err
+	}
+	if len(a) == 0 {
+		return nil
+	}
+	var v struct {
+		Location *Location `json:"location"`
+	}
+	if err := json.Unmarshal(a[0], &v); err != nil {
+		return err
+	}
+	if v.Location != nil {
+		return json.Unmarshal(data, &r.Information)
+	}
+	return json.Unmarshal(data, &r.Symbols)
+}
+
+// Wait waits for a response of document symbol request.
+func (r *DocumentSymbolResult) Wait() error {
+	return r.c.Wait(r.call)
+}
+
+// WaitContext is like Wait but gives up waiting when ctx is done.
+func (r *DocumentSymbolResult) WaitContext(ctx context.Context) error {
+	return r.c.WaitContext(ctx, r.call)
+}
+
+// WorkspaceSymbolParams represents the interface described in the specification.
+type WorkspaceSymbolParams struct {
+	Query string `json:"query"`
+}
+
+// WorkspaceSymbolResult represents a result object for workspace symbol request.
+type WorkspaceSymbolResult struct {
+	Symbols []SymbolInformation
+
+	c    *Client
+	call *Call
+}
+
+// WorkspaceSymbol sends the workspace symbol request to the server.
+func (c *Client) WorkspaceSymbol(params *WorkspaceSymbolParams) *WorkspaceSymbolResult {
+	var result WorkspaceSymbolResult
+	result.c = c
+	result.call = c.Call("workspace/symbol", params, &result.Symbols)
+	return &result
+}
+
+// Wait waits for a response of workspace symbol request.
+func (r *WorkspaceSymbolResult) Wait() error {
+	return r.c.Wait(r.call)
+}
+
+// WaitContext is like Wait but gives up waiting when ctx is done.
+func (r *WorkspaceSymbolResult) WaitContext(ctx context.Context) error {
+	return r.c.WaitContext(ctx, r.call)
+}
+
+// CallHierarchyItem represents the interface described in the specification.
+type CallHierarchyItem struct {
+	Name           string          `json:"name"`
+	Kind           SymbolKind      `json:"kind"`
+	Tags           []int           `json:"tags,omitempty"`
+	Detail         string          `json:"detail,omitempty"`
+	URI            DocumentURI     `json:"uri"`
+	Range          Range           `json:"range"`
+	SelectionRange Range           `json:"selectionRange"`
+	Data           json.RawMessage `json:"data,omitempty"`
+}
+
+// CallHierarchyItemsResult represents a result object for prepare call hierarchy request.
+type CallHierarchyItemsResult struct {
+	Items []CallHierarchyItem
+
+	c    *Client
+	call *Call
+}
+
+// PrepareCallHierarchy sends the prepare call hierarchy request to the server.
+func (c *Client) PrepareCallHierarchy(params *TextDocumentPositionParams) *CallHierarchyItemsResult {
+	var result CallHierarchyItemsResult
+	result.c = c
+	result.call = c.Call("textDocument/prepareCallHierarchy", params, &result.Items)
+	return &result
+}
+
+// Wait waits for a response of prepare call hierarchy request.
+func (r *CallHierarchyItemsResult) Wait() error {
+	return r.c.Wait(r.call)
+}
+
+// WaitContext is like Wait but gives up waiting when ctx is done.
+func (r *CallHierarchyItemsResult) WaitContext(ctx context.Context) error {
+	return r.c.WaitContext(ctx, r.call)
+}
+
+// CallHierarchyIncomingCallsParams represents the interface described in the specification.
+type CallHierarchyIncomingCallsParams struct {
+	Item CallHierarchyItem `json:"item"`
+}
+
+// CallHierarchyIncomingCall represents the interface described in the specification.
+type CallHierarchyIncomingCall struct {
+	From       CallHierarchyItem `json:"from"`
+	FromRanges []Range           `json:"fromRanges"`
+}
+
+// CallHierarchyIncomingCallsResult represents a result object for incoming calls request.
+type CallHierarchyIncomingCallsResult struct {
+	Calls []CallHierarchyIncomingCall
+
+	c    *Client
+	call *Call
+}
+
+// IncomingCalls sends the incoming calls request to the server.
+func (c *Client) IncomingCalls(params *CallHierarchyIncomingCallsParams) *CallHierarchyIncomingCallsResult {
+	var result CallHierarchyIncomingCallsResult
+	result.c = c
+	result.call = c.Call("callHierarchy/incomingCalls", params, &result.Calls)
+	return &result
+}
+
+// Wait waits for a response of incoming calls request.
+func (r *CallHierarchyIncomingCallsResult) Wait() error {
+	return r.c.Wait(r.call)
+}
+
+// WaitContext is like Wait but gives up waiting when ctx is done.
+func (r *CallHierarchyIncomingCallsResult) WaitContext(ctx context.Context) error {
+	return r.c.WaitContext(ctx, r.call)
+}
+
+// CallHierarchyOutgoingCallsParams represents the interface described in the specification.
+type CallHierarchyOutgoingCallsParams struct {
+	Item CallHierarchyItem `json:"item"`
+}
+
+// CallHierarchyOutgoingCall represents the interface described in the specification.
+type CallHierarchyOutgoingCall struct {
+	To         CallHierarchyItem `json:"to"`
+	FromRanges []Range           `json:"fromRanges"`
+}
+
+// CallHierarchyOutgoingCallsResult represents a result object for outgoing calls request.
+type CallHierarchyOutgoingCallsResult struct {
+	Calls []CallHierarchyOutgoingCall
+
+	c    *Client
+	call *Call
+}
+
+// OutgoingCalls sends the outgoing calls request to the server.
+func (c *Client) OutgoingCalls(params *CallHierarchyOutgoingCallsParams) *CallHierarchyOutgoingCallsResult {
+	var result CallHierarchyOutgoingCallsResult
+	result.c = c
+	result.call = c.Call("callHierarchy/outgoingCalls", params, &result.Calls)
+	return &result
+}
+
+// Wait waits for a response of outgoing calls request.
+func (r *CallHierarchyOutgoingCallsResult) Wait() error {
+	return r.c.Wait(r.call)
+}
+
+// WaitContext is like Wait but gives up waiting when ctx is done.
+func (r *CallHierarchyOutgoingCallsResult) WaitContext(ctx context.Context) error {
+	return r.c.WaitContext(ctx, r.call)
+}
+
+// TypeHierarchyItem represents the interface described in the specification.
+type TypeHierarchyItem struct {
+	Name           string          `json:"name"`
+	Kind           SymbolKind      `json:"kind"`
+	Tags           []int           `json:"tags,omitempty"`
+	Detail         string          `json:"detail,omitempty"`
+	URI            DocumentURI     `json:"uri"`
+	Range          Range           `json:"range"`
+	SelectionRange Range           `json:"selectionRange"`
+	Data           json.RawMessage `json:"data,omitempty"`
+}
+
+// TypeHierarchyItemsResult represents a result object for type hierarchy requests.
+type TypeHierarchyItemsResult struct {
+	Items []TypeHierarchyItem
+
+	c    *Client
+	call *Call
+}
+
+// PrepareTypeHierarchy sends the prepare type hierarchy request to the server.
+func (c *Client) PrepareTypeHierarchy(params *TextDocumentPositionParams) *TypeHierarchyItemsResult {
+	var result TypeHierarchyItemsResult
+	result.c = c
+	result.call = c.Call("textDocument/prepareTypeHierarchy", params, &result.Items)
+	return &result
+}
+
+// TypeHierarchySupertypesParams represents the interface described in the specification.
+type TypeHierarchySupertypesParams struct {
+	Item TypeHierarchyItem `json:"item"`
+}
+
+// Supertypes sends the type hierarchy supertypes request to the server.
+func (c *Client) Supertypes(params *TypeHierarchySupertypesParams) *TypeHierarchyItemsResult {
+	var result TypeHierarchyItemsResult
+	result.c = c
+	result.call = c.Call("typeHierarchy/supertypes", params, &result.Items)
+	return &result
+}
+
+// TypeHierarchySubtypesParams represents the interface described in the specification.
+type TypeHierarchySubtypesParams struct {
+	Item TypeHierarchyItem `json:"item"`
+}
+
+// Subtypes sends the type hierarchy subtypes request to the server.
+func (c *Client) Subtypes(params *TypeHierarchySubtypesParams) *TypeHierarchyItemsResult {
+	var result TypeHierarchyItemsResult
+	result.c = c
+	result.call = c.Call("typeHierarchy/subtypes", params, &result.Items)
+	return &result
+}
+
+// Wait waits for a response of any request.
+func (r *TypeHierarchyItemsResult) Wait() error {
+	return r.c.Wait(r.call)
+}
+
+// WaitContext is like Wait but gives up waiting when ctx is done.
+func (r *TypeHierarchyItemsResult) WaitContext(ctx context.Context) error {
+	return r.c.WaitContext(ctx, r.call)
+}
+
+// DocumentHighlightKind represents kinds of document highlights.
+type DocumentHighlightKind int
+
+// DocumentHighlightKind values defined in the specification.
+const (
+	DocumentHighlightKindText  DocumentHighlightKind = 1
+	DocumentHighlightKindRead  DocumentHighlightKind = 2
+	DocumentHighlightKindWrite DocumentHighlightKind = 3
+)
+
+// String returns a short name of k.
+func (k DocumentHighlightKind) String() string {
+	switch k {
+	case DocumentHighlightKindText:
+		return "text"
+	case DocumentHighlightKindRead:
+		return "read"
+	case DocumentHighlightKindWrite:
+		return "write"
+	default:
+		return fmt.Sprintf("kind(%d)", int(k))
+	}
+}
+
+// DocumentHighlight represents the interface described in the specification.
+// If Kind is omitted by the server, it is DocumentHighlightKindText.
+type DocumentHighlight struct {
+	Range Range                 `json:"range"`
+	Kind  DocumentHighlightKind `json:"kind,omitempty"`
+}
+
+// UnmarshalJSON implements json.Unmarshaler interface.
+func (h *DocumentHighlight) UnmarshalJSON(data []byte) error {
+	type documentHighlight DocumentHighlight
+	v := documentHighlight{Kind: DocumentHighlightKindText}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*h = DocumentHighlight(v)
+	return nil
+}
+
+// DocumentHighlightResult represents a result object for document highlight request.
+type DocumentHighlightResult struct {
+	Highlights []DocumentHighlight
+
+	c    *Client
+	call *Call
+}
+
+// DocumentHighlight sends the document highlight request to the server.
+func (c *Client) DocumentHighlight(params *TextDocumentPositionParams) *DocumentHighlightResult {
+	var result DocumentHighlightResult
+	result.c = c
+	result.call = c.Call("textDocument/documentHighlight", params, &result.Highlights)
+	return &result
+}
+
+// Wait waits for a response of document highlight request.
+func (r *DocumentHighlightResult) Wait() error {
+	return r.c.Wait(r.call)
+}
+
+// WaitContext is like Wait but gives up waiting when ctx is done.
+func (r *DocumentHighlightResult) WaitContext(ctx context.Context) error {
+	return r.c.WaitContext(ctx, r.call)
+}
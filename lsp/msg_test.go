@@ -0,0 +1,136 @@
+package lsp
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestDocumentSymbolsUnmarshalJSON(t *testing.T) {
+	r := Range{
+		Start: Position{Line: 2, Character: 0},
+		End:   Position{Line: 4, Character: 1},
+	}
+	tests := []struct {
+		name string
+		data string
+		want DocumentSymbolResult
+	}{
+		{
+			name: "hierarchical",
+			data: `[{"name":"T","kind":23,"range":{"start":{"line":2,"character":0},"end":{"line":4,"character":1}},"selectionRange":{"start":{"line":2,"character":0},"end":{"line":4,"character":1}},
+				"children":[{"name":"X","kind":8,"range":{"start":{"line":2,"character":0},"end":{"line":4,"character":1}},"selectionRange":{"start":{"line":2,"character":0},"end":{"line":4,"character":1}}}]}]`,
+			want: DocumentSymbolResult{
+				Symbols: []DocumentSymbol{
+					{
+						Name:           "T",
+						Kind:           SymbolKindStruct,
+						Range:          r,
+						SelectionRange: r,
+						Children: []DocumentSymbol{
+							{Name: "X", Kind: SymbolKindField, Range: r, SelectionRange: r},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "flat",
+			data: `[{"name":"X","kind":8,"location":{"uri":"file:///pkg.go","range":{"start":{"line":2,"character":0},"end":{"line":4,"character":1}}},"containerName":"T"}]`,
+			want: DocumentSymbolResult{
+				Information: []SymbolInformation{
+					{
+						Name:          "X",
+						Kind:          SymbolKindField,
+						Location:      Location{URI: "file:///pkg.go", Range: r},
+						ContainerName: "T",
+					},
+				},
+			},
+		},
+		{
+			name: "empty",
+			data: `[]`,
+			want: DocumentSymbolResult{},
+		},
+		{
+			name: "null",
+			data: `null`,
+			want: DocumentSymbolResult{},
+		},
+	}
+	for _, tt := range tests {
+		var v DocumentSymbolResult
+		if err := json.Unmarshal([]byte(tt.data), (*documentSymbols)(&v)); err != nil {
+			t.Errorf("%s: Unmarshal: %v", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(v, tt.want) {
+			t.Errorf("%s: Unmarshal = %+v; want %+v", tt.name, v, tt.want)
+		}
+	}
+}
+
+func TestLocationsUnmarshalJSON(t *testing.T) {
+	r := Range{
+		Start: Position{Line: 3, Character: 5},
+		End:   Position{Line: 3, Character: 6},
+	}
+	body := Range{
+		Start: Position{Line: 3, Character: 0},
+		End:   Position{Line: 5, Character: 1},
+	}
+	loc := Location{URI: "file:///pkg.go", Range: r}
+	tests := []struct {
+		name string
+		data string
+		want []Location
+	}{
+		{
+			name: "Location",
+			data: `{"uri":"file:///pkg.go","range":{"start":{"line":3,"character":5},"end":{"line":3,"character":6}}}`,
+			want: []Location{loc},
+		},
+		{
+			name: "Location array",
+			data: `[
+				{"uri":"file:///pkg.go","range":{"start":{"line":3,"character":5},"end":{"line":3,"character":6}}},
+				{"uri":"file:///a.go","range":{"start":{"line":3,"character":0},"end":{"line":5,"character":1}}}
+			]`,
+			want: []Location{loc, {URI: "file:///a.go", Range: body}},
+		},
+		{
+			name: "LocationLink",
+			data: `[{"targetUri":"file:///pkg.go",
+				"targetRange":{"start":{"line":3,"character":0},"end":{"line":5,"character":1}},
+				"targetSelectionRange":{"start":{"line":3,"character":5},"end":{"line":3,"character":6}}}]`,
+			want: []Location{loc},
+		},
+		{
+			name: "LocationLink without selection range",
+			data: `[{"targetUri":"file:///pkg.go",
+				"targetRange":{"start":{"line":3,"character":0},"end":{"line":5,"character":1}}}]`,
+			want: []Location{{URI: "file:///pkg.go", Range: body}},
+		},
+		{
+			name: "empty",
+			data: `[]`,
+			want: nil,
+		},
+		{
+			name: "null",
+			data: `null`,
+			want: nil,
+		},
+	}
+	for _, tt := range tests {
+		var a []Location
+		if err := json.Unmarshal([]byte(tt.data), (*locations)(&a)); err != nil {
+			t.Errorf("%s: Unmarshal: %v", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(a, tt.want) {
+			t.Errorf("%s: Unmarshal = %+v; want %+v", tt.name, a, tt.want)
+		}
+	}
+}
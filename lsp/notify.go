@@ -0,0 +1,127 @@
+package lsp
+
+import (
+	"encoding/json"
+	"sync"
+)
+
+// notifyQueue is an unbounded queue of notifications.
+type notifyQueue struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	msgs   []*Message
+	closed bool
+}
+
+func newNotifyQueue() *notifyQueue {
+	q := &notifyQueue{}
+	q.cond = sync.NewCond(&q.mu)
+	return q
+}
+
+func (q *notifyQueue) push(msg *Message) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.msgs = append(q.msgs, msg)
+	q.cond.Signal()
+}
+
+// pop returns a message at the head of q.
+// After q is closed and all messages are popped, pop returns nil.
+func (q *notifyQueue) pop() *Message {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for len(q.msgs) == 0 {
+		if q.closed {
+			return nil
+		}
+		q.cond.Wait()
+	}
+	msg := q.msgs[0]
+	q.msgs[0] = nil
+	q.msgs = q.msgs[1:]
+	return msg
+}
+
+func (q *notifyQueue) close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.closed = true
+	q.cond.Signal()
+}
+
+// OnNotification registers fn as the handler for notifications of method.
+// Notifications that have the handler are delivered to it in order of arrival;
+// they are never dropped even if the handler is slow.
+// Other notifications are sent to c.Event.
+func (c *Client) OnNotification(method string, fn func(params json.RawMessage)) {
+	c.nmu.Lock()
+	defer c.nmu.Unlock()
+	c.notifiers[method] = fn
+}
+
+func (c *Client) notifier(method string) func(params json.RawMessage) {
+	c.nmu.Lock()
+	defer c.nmu.Unlock()
+	return c.notifiers[method]
+}
+
+func (c *Client) dispatch() {
+	for {
+		msg := c.q.pop()
+		if msg == nil {
+			return
+		}
+		if fn := c.notifier(msg.Method); fn != nil {
+			fn(msg.Params)
+		}
+	}
+}
+
+// onNotification is like OnNotification but it decodes params into v before calling fn.
+func (c *Client) onNotification(method string, newParams func() interface{}, fn func(v interface{})) {
+	c.OnNotification(method, func(params json.RawMessage) {
+		v := newParams()
+		if err := json.Unmarshal(params, v); err != nil {
+			c.debugf("can't unmarshal %s: %v\n", method, err)
+			return
+		}
+		fn(v)
+	})
+}
+
+// OnPublishDiagnostics registers fn as the handler for textDocument/publishDiagnostics notifications.
+func (c *Client) OnPublishDiagnostics(fn func(params *PublishDiagnosticsParams)) {
+	c.onNotification("textDocument/publishDiagnostics", func() interface{} {
+		return &PublishDiagnosticsParams{}
+	}, func(v interface{}) {
+		fn(v.(*PublishDiagnosticsParams))
+	})
+}
+
+// OnShowMessage registers fn as the handler for window/showMessage notifications.
+func (c *Client) OnShowMessage(fn func(params *ShowMessageParams)) {
+	c.onNotification("window/showMessage", func() interface{} {
+		return &ShowMessageParams{}
+	}, func(v interface{}) {
+		fn(v.(*ShowMessageParams))
+	})
+}
+
+// OnLogMessage registers fn as the handler for window/logMessage notifications.
+func (c *Client) OnLogMessage(fn func(params *LogMessageParams)) {
+	c.onNotification("window/logMessage", func() interface{} {
+		return &LogMessageParams{}
+	}, func(v interface{}) {
+		fn(v.(*LogMessageParams))
+	})
+}
+
+// OnProgress registers fn as the handler for $/progress notifications.
+func (c *Client) OnProgress(fn func(params *ProgressParams)) {
+	c.onNotification("$/progress", func() interface{} {
+		return &ProgressParams{}
+	}, func(v interface{}) {
+		fn(v.(*ProgressParams))
+	})
+}
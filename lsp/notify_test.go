@@ -0,0 +1,64 @@
+package lsp_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/lufia/acme-lsp/lsp"
+)
+
+func TestOnNotification(t *testing.T) {
+	c, s := newTestClient(t)
+
+	const n = 100
+	got := make(chan string, n)
+	c.OnLogMessage(func(params *lsp.LogMessageParams) {
+		time.Sleep(time.Millisecond) // slow handler
+		got <- params.Message
+	})
+
+	// more notifications than the capacity of c.Event.
+	for i := 0; i < n; i++ {
+		err := s.Notify("window/logMessage", &lsp.LogMessageParams{
+			Type:    lsp.MessageTypeLog,
+			Message: fmt.Sprintf("message %d", i),
+		})
+		if err != nil {
+			t.Fatalf("Notify: %v", err)
+		}
+	}
+	for i := 0; i < n; i++ {
+		want := fmt.Sprintf("message %d", i)
+		select {
+		case s := <-got:
+			if s != want {
+				t.Fatalf("Message = %q; want %q", s, want)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for %q", want)
+		}
+	}
+}
+
+func TestOnNotificationFallback(t *testing.T) {
+	c, s := newTestClient(t)
+	c.OnPublishDiagnostics(func(params *lsp.PublishDiagnosticsParams) {
+		t.Errorf("unexpected diagnostics: %v", params)
+	})
+	err := s.Notify("window/showMessage", &lsp.ShowMessageParams{
+		Type:    lsp.MessageTypeInfo,
+		Message: "hello",
+	})
+	if err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+	select {
+	case msg := <-c.Event:
+		if msg.Method != "window/showMessage" {
+			t.Errorf("Method = %q; want window/showMessage", msg.Method)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out")
+	}
+}
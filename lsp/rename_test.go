@@ -0,0 +1,188 @@
+package lsp_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/lufia/acme-lsp/lsp"
+)
+
+func TestRenameRangeUnmarshalJSON(t *testing.T) {
+	r := lsp.Range{
+		Start: lsp.Position{Line: 1, Character: 2},
+		End:   lsp.Position{Line: 1, Character: 5},
+	}
+	tests := []struct {
+		name string
+		data string
+		want *lsp.RenameRange
+	}{
+		{
+			name: "Range",
+			data: `{"start":{"line":1,"character":2},"end":{"line":1,"character":5}}`,
+			want: &lsp.RenameRange{Range: r},
+		},
+		{
+			name: "Range with placeholder",
+			data: `{"range":{"start":{"line":1,"character":2},"end":{"line":1,"character":5}},"placeholder":"abc"}`,
+			want: &lsp.RenameRange{Range: r, Placeholder: "abc"},
+		},
+		{
+			name: "default behavior",
+			data: `{"defaultBehavior":true}`,
+			want: &lsp.RenameRange{DefaultBehavior: true},
+		},
+		{
+			name: "null",
+			data: `null`,
+			want: nil,
+		},
+	}
+	for _, tt := range tests {
+		var v *lsp.RenameRange
+		if err := json.Unmarshal([]byte(tt.data), &v); err != nil {
+			t.Errorf("%s: Unmarshal: %v", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(v, tt.want) {
+			t.Errorf("%s: Unmarshal = %+v; want %+v", tt.name, v, tt.want)
+		}
+	}
+}
+
+func TestRenameOptionsUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		data string
+		want lsp.RenameOptions
+	}{
+		{`false`, lsp.RenameOptions{}},
+		{`true`, lsp.RenameOptions{Enabled: true}},
+		{`{}`, lsp.RenameOptions{Enabled: true}},
+		{`{"prepareProvider":true}`, lsp.RenameOptions{Enabled: true, PrepareProvider: true}},
+	}
+	for _, tt := range tests {
+		var o lsp.RenameOptions
+		if err := json.Unmarshal([]byte(tt.data), &o); err != nil {
+			t.Errorf("Unmarshal(%s): %v", tt.data, err)
+			continue
+		}
+		if o != tt.want {
+			t.Errorf("Unmarshal(%s) = %+v; want %+v", tt.data, o, tt.want)
+		}
+	}
+}
+
+func TestWorkspaceEditEdits(t *testing.T) {
+	edit := func(line int, text string) lsp.TextEdit {
+		return lsp.TextEdit{
+			Range: lsp.Range{
+				Start: lsp.Position{Line: line, Character: 0},
+				End:   lsp.Position{Line: line, Character: 1},
+			},
+			NewText: text,
+		}
+	}
+	tests := []struct {
+		name string
+		data string
+		want map[lsp.DocumentURI][]lsp.TextEdit
+	}{
+		{
+			name: "changes",
+			data: `{"changes":{"file:///a.go":[{"range":{"start":{"line":0,"character":0},"end":{"line":0,"character":1}},"newText":"x"}]}}`,
+			want: map[lsp.DocumentURI][]lsp.TextEdit{
+				"file:///a.go": {edit(0, "x")},
+			},
+		},
+		{
+			name: "documentChanges",
+			data: `{"documentChanges":[
+				{"textDocument":{"uri":"file:///a.go","version":1},"edits":[{"range":{"start":{"line":2,"character":0},"end":{"line":2,"character":1}},"newText":"y"}]},
+				{"textDocument":{"uri":"file:///a.go","version":1},"edits":[{"range":{"start":{"line":3,"character":0},"end":{"line":3,"character":1}},"newText":"z"}]}
+			]}`,
+			want: map[lsp.DocumentURI][]lsp.TextEdit{
+				"file:///a.go": {edit(2, "y"), edit(3, "z")},
+			},
+		},
+		{
+			name: "both",
+			data: `{
+				"changes":{"file:///a.go":[{"range":{"start":{"line":0,"character":0},"end":{"line":0,"character":1}},"newText":"x"}]},
+				"documentChanges":[{"textDocument":{"uri":"file:///b.go","version":null},"edits":[{"range":{"start":{"line":2,"character":0},"end":{"line":2,"character":1}},"newText":"y"}]}]
+			}`,
+			want: map[lsp.DocumentURI][]lsp.TextEdit{
+				"file:///a.go": {edit(0, "x")},
+				"file:///b.go": {edit(2, "y")},
+			},
+		},
+		{
+			name: "empty",
+			data: `{}`,
+			want: map[lsp.DocumentURI][]lsp.TextEdit{},
+		},
+	}
+	for _, tt := range tests {
+		var e lsp.WorkspaceEdit
+		if err := json.Unmarshal([]byte(tt.data), &e); err != nil {
+			t.Errorf("%s: Unmarshal: %v", tt.name, err)
+			continue
+		}
+		if edits := e.Edits(); !reflect.DeepEqual(edits, tt.want) {
+			t.Errorf("%s: Edits() = %+v; want %+v", tt.name, edits, tt.want)
+		}
+	}
+}
+
+func TestTextDocumentEditVersion(t *testing.T) {
+	tests := []struct {
+		data string
+		want *int
+	}{
+		{`{"textDocument":{"uri":"file:///a.go","version":3},"edits":[]}`, intPtr(3)},
+		{`{"textDocument":{"uri":"file:///a.go","version":null},"edits":[]}`, nil},
+		{`{"textDocument":{"uri":"file:///a.go"},"edits":[]}`, nil},
+	}
+	for _, tt := range tests {
+		var e lsp.TextDocumentEdit
+		if err := json.Unmarshal([]byte(tt.data), &e); err != nil {
+			t.Errorf("Unmarshal(%s): %v", tt.data, err)
+			continue
+		}
+		if v := e.TextDocument.Version; !reflect.DeepEqual(v, tt.want) {
+			t.Errorf("Unmarshal(%s).TextDocument.Version = %v; want %v", tt.data, v, tt.want)
+		}
+	}
+}
+
+func intPtr(n int) *int {
+	return &n
+}
+
+func TestRename(t *testing.T) {
+	c, s := newTestClient(t)
+	s.HandleResult("textDocument/rename", json.RawMessage(`{"changes":{}}`))
+	result := c.Rename(&lsp.RenameParams{
+		TextDocumentPositionParams: lsp.TextDocumentPositionParams{
+			TextDocument: lsp.TextDocumentIdentifier{URI: c.URL("pkg.go")},
+		},
+		NewName: "x",
+	})
+	if err := result.Wait(); err != nil {
+		t.Fatalf("Rename: %v", err)
+	}
+	if result.Edit == nil {
+		t.Errorf("Edit = nil; want an empty edit")
+	}
+	a := s.Messages("textDocument/rename")
+	if len(a) != 1 {
+		t.Fatalf("received %d rename requests; want 1", len(a))
+	}
+	var params lsp.RenameParams
+	if err := json.Unmarshal(a[0].Params, &params); err != nil {
+		t.Fatal(err)
+	}
+	if params.NewName != "x" {
+		t.Errorf("NewName = %q; want x", params.NewName)
+	}
+}
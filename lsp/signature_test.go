@@ -0,0 +1,176 @@
+package lsp_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/lufia/acme-lsp/lsp"
+)
+
+func TestParameterLabelUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		data string
+		want lsp.ParameterLabel
+	}{
+		{`"format string"`, lsp.ParameterLabel{Text: "format string"}},
+		{`[22, 30]`, lsp.ParameterLabel{Offsets: &[2]int{22, 30}}},
+		{`""`, lsp.ParameterLabel{}},
+	}
+	for _, tt := range tests {
+		var l lsp.ParameterLabel
+		if err := json.Unmarshal([]byte(tt.data), &l); err != nil {
+			t.Errorf("Unmarshal(%s): %v", tt.data, err)
+			continue
+		}
+		if !reflect.DeepEqual(l, tt.want) {
+			t.Errorf("Unmarshal(%s) = %+v; want %+v", tt.data, l, tt.want)
+		}
+		b, err := json.Marshal(l)
+		if err != nil {
+			t.Errorf("Marshal(%+v): %v", l, err)
+			continue
+		}
+		var v, w interface{}
+		json.Unmarshal(b, &v)
+		json.Unmarshal([]byte(tt.data), &w)
+		if !reflect.DeepEqual(v, w) {
+			t.Errorf("Marshal(%+v) = %s; want %s", l, b, tt.data)
+		}
+	}
+}
+
+func TestParameterLabelRange(t *testing.T) {
+	tests := []struct {
+		label      lsp.ParameterLabel
+		s          string
+		start, end int
+		ok         bool
+	}{
+		{lsp.ParameterLabel{Text: "a int"}, "F(a int, b int)", 2, 7, true},
+		{lsp.ParameterLabel{Text: "b int"}, "F(a int, b int)", 9, 14, true},
+		{lsp.ParameterLabel{Text: "b int"}, "F(名前 string, b int)", 13, 18, true},
+		{lsp.ParameterLabel{Text: "c int"}, "F(a int, b int)", 0, 0, false},
+		{lsp.ParameterLabel{}, "F(a int, b int)", 0, 0, false},
+		{lsp.ParameterLabel{Offsets: &[2]int{9, 14}}, "F(a int, b int)", 9, 14, true},
+		{lsp.ParameterLabel{Offsets: &[2]int{2, 4}}, "F(名前 string)", 2, 4, true},
+		{lsp.ParameterLabel{Offsets: &[2]int{9, 20}}, "F(a int, b int)", 0, 0, false},
+		{lsp.ParameterLabel{Offsets: &[2]int{5, 3}}, "F(a int, b int)", 0, 0, false},
+	}
+	for _, tt := range tests {
+		start, end, ok := tt.label.Range(tt.s)
+		if start != tt.start || end != tt.end || ok != tt.ok {
+			t.Errorf("Range(%q) of %+v = %d, %d, %v; want %d, %d, %v", tt.s, tt.label, start, end, ok, tt.start, tt.end, tt.ok)
+		}
+	}
+}
+
+func TestSignatureHelpUnmarshalJSON(t *testing.T) {
+	one := 1
+	tests := []struct {
+		name string
+		data string
+		want *lsp.SignatureHelp
+	}{
+		{
+			name: "offsets",
+			data: `{
+				"signatures": [{
+					"label": "Printf(format string, a ...any)",
+					"documentation": "Printf formats.",
+					"parameters": [{"label": [7, 20]}, {"label": [22, 30]}],
+					"activeParameter": 1
+				}]
+			}`,
+			want: &lsp.SignatureHelp{
+				Signatures: []lsp.SignatureInformation{
+					{
+						Label:         "Printf(format string, a ...any)",
+						Documentation: &lsp.MarkupContent{Kind: lsp.MarkupKindPlainText, Value: "Printf formats."},
+						Parameters: []lsp.ParameterInformation{
+							{Label: lsp.ParameterLabel{Offsets: &[2]int{7, 20}}},
+							{Label: lsp.ParameterLabel{Offsets: &[2]int{22, 30}}},
+						},
+						ActiveParameter: &one,
+					},
+				},
+			},
+		},
+		{
+			name: "strings",
+			data: `{
+				"signatures": [{
+					"label": "Println(a ...any)",
+					"parameters": [{"label": "a ...any", "documentation": {"kind": "markdown", "value": "*a*"}}]
+				}],
+				"activeSignature": 0,
+				"activeParameter": 0
+			}`,
+			want: &lsp.SignatureHelp{
+				Signatures: []lsp.SignatureInformation{
+					{
+						Label: "Println(a ...any)",
+						Parameters: []lsp.ParameterInformation{
+							{
+								Label:         lsp.ParameterLabel{Text: "a ...any"},
+								Documentation: &lsp.MarkupContent{Kind: lsp.MarkupKindMarkdown, Value: "*a*"},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "null",
+			data: `null`,
+			want: nil,
+		},
+	}
+	for _, tt := range tests {
+		var h *lsp.SignatureHelp
+		if err := json.Unmarshal([]byte(tt.data), &h); err != nil {
+			t.Errorf("%s: Unmarshal: %v", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(h, tt.want) {
+			t.Errorf("%s: Unmarshal = %+v; want %+v", tt.name, h, tt.want)
+		}
+	}
+}
+
+func TestSignatureHelp(t *testing.T) {
+	c, s := newTestClient(t)
+	s.HandleResult("textDocument/signatureHelp", json.RawMessage(`{"signatures":[{"label":"F()"}]}`))
+	r := c.SignatureHelp(&lsp.SignatureHelpParams{
+		TextDocumentPositionParams: lsp.TextDocumentPositionParams{
+			TextDocument: lsp.TextDocumentIdentifier{URI: c.URL("pkg.go")},
+		},
+		Context: &lsp.SignatureHelpContext{
+			TriggerKind:      lsp.SignatureHelpTriggerKindTriggerCharacter,
+			TriggerCharacter: ",",
+			IsRetrigger:      true,
+		},
+	})
+	if err := r.Wait(); err != nil {
+		t.Fatalf("SignatureHelp: %v", err)
+	}
+	if r.Help == nil || len(r.Help.Signatures) != 1 {
+		t.Fatalf("SignatureHelp = %+v; want 1 signature", r.Help)
+	}
+	a := s.Messages("textDocument/signatureHelp")
+	if len(a) != 1 {
+		t.Fatalf("received %d signatureHelp requests; want 1", len(a))
+	}
+	var p lsp.SignatureHelpParams
+	if err := json.Unmarshal(a[0].Params, &p); err != nil {
+		t.Fatal(err)
+	}
+	want := lsp.SignatureHelpContext{
+		TriggerKind:      lsp.SignatureHelpTriggerKindTriggerCharacter,
+		TriggerCharacter: ",",
+		IsRetrigger:      true,
+	}
+	if p.Context == nil || *p.Context != want {
+		t.Errorf("Context = %+v; want %+v", p.Context, want)
+	}
+}
@@ -0,0 +1,76 @@
+package lsp_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/lufia/acme-lsp/lsp"
+)
+
+func TestSymbolKindString(t *testing.T) {
+	tests := []struct {
+		kind lsp.SymbolKind
+		want string
+	}{
+		{lsp.SymbolKindFile, "file"},
+		{lsp.SymbolKindFunction, "func"},
+		{lsp.SymbolKindTypeParameter, "typeparam"},
+		{0, "kind(0)"},
+		{100, "kind(100)"},
+	}
+	for _, tt := range tests {
+		if s := tt.kind.String(); s != tt.want {
+			t.Errorf("SymbolKind(%d).String() = %q; want %q", int(tt.kind), s, tt.want)
+		}
+	}
+}
+
+func TestSymbolRequests(t *testing.T) {
+	c, s := newTestClient(t)
+	s.HandleResult("textDocument/documentSymbol", json.RawMessage(`[{"name":"T","kind":23}]`))
+	s.HandleResult("workspace/symbol", json.RawMessage(`[{"name":"T","kind":23,"location":{"uri":"file:///a.go"}}]`))
+	tests := []struct {
+		method string
+		call   func() (int, error)
+		params string
+	}{
+		{
+			method: "textDocument/documentSymbol",
+			call: func() (int, error) {
+				r := c.DocumentSymbol(&lsp.DocumentSymbolParams{
+					TextDocument: lsp.TextDocumentIdentifier{URI: "file:///a.go"},
+				})
+				err := r.Wait()
+				return len(r.Symbols), err
+			},
+			params: `{"textDocument":{"uri":"file:///a.go"}}`,
+		},
+		{
+			method: "workspace/symbol",
+			call: func() (int, error) {
+				r := c.WorkspaceSymbol(&lsp.WorkspaceSymbolParams{Query: "T"})
+				err := r.Wait()
+				return len(r.Symbols), err
+			},
+			params: `{"query":"T"}`,
+		},
+	}
+	for _, tt := range tests {
+		n, err := tt.call()
+		if err != nil {
+			t.Errorf("%s: %v", tt.method, err)
+			continue
+		}
+		if n != 1 {
+			t.Errorf("%s: %d symbols; want 1", tt.method, n)
+		}
+		a := s.Messages(tt.method)
+		if len(a) != 1 {
+			t.Errorf("received %d %s requests; want 1", len(a), tt.method)
+			continue
+		}
+		if s := string(a[0].Params); s != tt.params {
+			t.Errorf("%s: params = %s; want %s", tt.method, s, tt.params)
+		}
+	}
+}
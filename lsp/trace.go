@@ -0,0 +1,240 @@
+package lsp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Directions of the message in a trace.
+const (
+	TraceSend = "send" // client to server
+	TraceRecv = "recv" // server to client
+)
+
+// TraceEntry represents a message recorded in a trace.
+// A trace is a sequence of TraceEntry encoded in JSON, one per line.
+type TraceEntry struct {
+	Time    time.Time       `json:"time"`
+	Dir     string          `json:"dir"`
+	Message json.RawMessage `json:"message"`
+}
+
+// Recorder is a connection that records messages passing through it to a trace.
+type Recorder struct {
+	conn io.ReadWriteCloser
+
+	mu   sync.Mutex // protects enc
+	enc  *json.Encoder
+	rbuf []byte // incomplete frames read
+	wbuf []byte // incomplete frames written
+}
+
+// NewRecorder returns a Recorder that records messages between conn to w.
+func NewRecorder(conn io.ReadWriteCloser, w io.Writer) *Recorder {
+	return &Recorder{
+		conn: conn,
+		enc:  json.NewEncoder(w),
+	}
+}
+
+// Read reads bytes from the server, then records messages completed by them.
+func (r *Recorder) Read(b []byte) (int, error) {
+	n, err := r.conn.Read(b)
+	r.rbuf = r.record(TraceRecv, append(r.rbuf, b[:n]...))
+	return n, err
+}
+
+// Write records messages completed by b, then writes b to the server.
+// Messages are recorded before they are written
+// so that responses to them are never recorded before them.
+func (r *Recorder) Write(b []byte) (int, error) {
+	r.wbuf = r.record(TraceSend, append(r.wbuf, b...))
+	return r.conn.Write(b)
+}
+
+// Close closes underlying connection.
+func (r *Recorder) Close() error {
+	return r.conn.Close()
+}
+
+// record writes complete frames in buf, then returns the rest.
+func (r *Recorder) record(dir string, buf []byte) []byte {
+	for {
+		body, n, ok := splitFrame(buf)
+		if !ok {
+			return buf
+		}
+		r.mu.Lock()
+		r.enc.Encode(&TraceEntry{
+			Time:    time.Now(),
+			Dir:     dir,
+			Message: json.RawMessage(body),
+		})
+		r.mu.Unlock()
+		buf = buf[n:]
+	}
+}
+
+// splitFrame returns the body of the first frame in buf and the length of the frame.
+// If buf don't contain a complete frame, ok is false.
+func splitFrame(buf []byte) (body []byte, n int, ok bool) {
+	i := bytes.Index(buf, []byte("\r\n\r\n"))
+	if i < 0 {
+		return nil, 0, false
+	}
+	var contentLen int
+	for _, s := range strings.Split(string(buf[:i]), "\r\n") {
+		a := strings.SplitN(s, ":", 2)
+		if len(a) < 2 {
+			continue
+		}
+		if strings.TrimSpace(a[0]) == "Content-Length" {
+			contentLen, _ = strconv.Atoi(strings.TrimSpace(a[1]))
+		}
+	}
+	n = i + 4 + contentLen
+	if len(buf) < n {
+		return nil, 0, false
+	}
+	return buf[i+4 : n], n, true
+}
+
+// ReadTrace reads all entries of a trace from r.
+func ReadTrace(r io.Reader) ([]TraceEntry, error) {
+	var entries []TraceEntry
+	s := bufio.NewScanner(r)
+	s.Buffer(nil, 1<<26)
+	for s.Scan() {
+		if len(bytes.TrimSpace(s.Bytes())) == 0 {
+			continue
+		}
+		var e TraceEntry
+		if err := json.Unmarshal(s.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("can't parse trace: %w", err)
+		}
+		entries = append(entries, e)
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// Replayer is a fake connection that serves messages recorded in a trace.
+//
+// Replayer behaves as the server: at first, it serves received messages
+// until the client sent a message in the trace.
+// Then Replayer waits for the client to send the same message.
+// After that, it serves subsequent received messages again.
+type Replayer struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	entries []TraceEntry
+	out     bytes.Buffer // frames to be read by the client
+	in      []byte       // incomplete frames written by the client
+	closed  bool
+}
+
+// NewReplayer returns a Replayer that serves the trace read from r.
+func NewReplayer(r io.Reader) (*Replayer, error) {
+	entries, err := ReadTrace(r)
+	if err != nil {
+		return nil, err
+	}
+	p := &Replayer{entries: entries}
+	p.cond = sync.NewCond(&p.mu)
+	p.serve()
+	return p, nil
+}
+
+// serve moves received messages until next sent message to p.out.
+func (p *Replayer) serve() {
+	for len(p.entries) > 0 && p.entries[0].Dir == TraceRecv {
+		m := p.entries[0].Message
+		fmt.Fprintf(&p.out, "Content-Length: %d\r\n\r\n", len(m))
+		p.out.Write(m)
+		p.entries = p.entries[1:]
+	}
+	p.cond.Broadcast()
+}
+
+// Read reads recorded messages from the server.
+// Read returns io.EOF after all messages in the trace are served.
+func (p *Replayer) Read(b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for p.out.Len() == 0 {
+		if p.closed || len(p.entries) == 0 {
+			return 0, io.EOF
+		}
+		p.cond.Wait()
+	}
+	return p.out.Read(b)
+}
+
+// Write writes messages to the server.
+// If a message differs from recorded one, Write returns an error.
+func (p *Replayer) Write(b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return 0, errReplayerClosed
+	}
+	p.in = append(p.in, b...)
+	for {
+		body, n, ok := splitFrame(p.in)
+		if !ok {
+			return len(b), nil
+		}
+		p.in = p.in[n:]
+		if err := p.match(body); err != nil {
+			return 0, err
+		}
+		p.serve()
+	}
+}
+
+var errReplayerClosed = errors.New("replayer is closed")
+
+// match reports whether body matches the next message that the client sent in the trace.
+func (p *Replayer) match(body []byte) error {
+	if len(p.entries) == 0 {
+		return fmt.Errorf("unexpected message: %s", body)
+	}
+	var got, want Message
+	if err := json.Unmarshal(body, &got); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(p.entries[0].Message, &want); err != nil {
+		return err
+	}
+	if got.Method != want.Method || !equalID(got.ID, want.ID) {
+		return fmt.Errorf("unexpected message: %s; want %s", body, p.entries[0].Message)
+	}
+	p.entries = p.entries[1:]
+	return nil
+}
+
+func equalID(a, b *ID) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// Close closes p. After Close, Read returns io.EOF.
+func (p *Replayer) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closed = true
+	p.cond.Broadcast()
+	return nil
+}
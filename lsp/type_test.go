@@ -0,0 +1,92 @@
+package lsp_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/lufia/acme-lsp/lsp"
+)
+
+const testTypeItemJSON = `{"name":"Reader","kind":11,"detail":"io","uri":"file:///io.go",` +
+	`"range":{"start":{"line":3,"character":0},"end":{"line":5,"character":1}},` +
+	`"selectionRange":{"start":{"line":3,"character":5},"end":{"line":3,"character":11}},` +
+	`"data":[1,2]}`
+
+var testTypeItem = lsp.TypeHierarchyItem{
+	Name:   "Reader",
+	Kind:   lsp.SymbolKindInterface,
+	Detail: "io",
+	URI:    "file:///io.go",
+	Range: lsp.Range{
+		Start: lsp.Position{Line: 3, Character: 0},
+		End:   lsp.Position{Line: 5, Character: 1},
+	},
+	SelectionRange: lsp.Range{
+		Start: lsp.Position{Line: 3, Character: 5},
+		End:   lsp.Position{Line: 3, Character: 11},
+	},
+	Data: json.RawMessage(`[1,2]`),
+}
+
+func TestTypeHierarchyRequests(t *testing.T) {
+	c, s := newTestClient(t)
+	s.HandleResult("textDocument/prepareTypeHierarchy", json.RawMessage(`[`+testTypeItemJSON+`]`))
+	s.HandleResult("typeHierarchy/supertypes", json.RawMessage(`null`))
+	s.HandleResult("typeHierarchy/subtypes", json.RawMessage(`[`+testTypeItemJSON+`]`))
+	tests := []struct {
+		method string
+		call   func() (*lsp.TypeHierarchyItemsResult, error)
+		params string
+		n      int
+	}{
+		{
+			method: "textDocument/prepareTypeHierarchy",
+			call: func() (*lsp.TypeHierarchyItemsResult, error) {
+				r := c.PrepareTypeHierarchy(&lsp.TextDocumentPositionParams{
+					TextDocument: lsp.TextDocumentIdentifier{URI: "file:///io.go"},
+					Position:     lsp.Position{Line: 3, Character: 5},
+				})
+				return r, r.Wait()
+			},
+			params: `{"textDocument":{"uri":"file:///io.go"},"position":{"line":3,"character":5}}`,
+			n:      1,
+		},
+		{
+			// the item, including its data, must be sent back as it is.
+			method: "typeHierarchy/supertypes",
+			call: func() (*lsp.TypeHierarchyItemsResult, error) {
+				r := c.Supertypes(&lsp.TypeHierarchySupertypesParams{Item: testTypeItem})
+				return r, r.Wait()
+			},
+			params: `{"item":` + testTypeItemJSON + `}`,
+			n:      0,
+		},
+		{
+			method: "typeHierarchy/subtypes",
+			call: func() (*lsp.TypeHierarchyItemsResult, error) {
+				r := c.Subtypes(&lsp.TypeHierarchySubtypesParams{Item: testTypeItem})
+				return r, r.Wait()
+			},
+			params: `{"item":` + testTypeItemJSON + `}`,
+			n:      1,
+		},
+	}
+	for _, tt := range tests {
+		r, err := tt.call()
+		if err != nil {
+			t.Errorf("%s: %v", tt.method, err)
+			continue
+		}
+		if len(r.Items) != tt.n {
+			t.Errorf("%s: %d items; want %d", tt.method, len(r.Items), tt.n)
+		}
+		a := s.Messages(tt.method)
+		if len(a) != 1 {
+			t.Errorf("received %d %s requests; want 1", len(a), tt.method)
+			continue
+		}
+		if s := string(a[0].Params); s != tt.params {
+			t.Errorf("%s: params = %s; want %s", tt.method, s, tt.params)
+		}
+	}
+}
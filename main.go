@@ -1,38 +1,122 @@
 package main
 
 import (
+	"encoding/json"
 	"flag"
+	"io"
 	"log"
+	"os"
+	"os/exec"
+	"time"
 
 	"9fans.net/go/acme"
 	"github.com/lufia/acme-lsp/lsp"
 )
 
 var (
-	debugFlag = flag.Bool("d", false, "enable debigging logs")
+	debugFlag   = flag.Bool("d", false, "enable debigging logs")
+	timeoutFlag = flag.Duration("t", 10*time.Second, "give up waiting for a response after `duration`")
+	remoteFlag  = flag.String("remote", "", "connect to the server listening on `addr` instead of starting gopls")
+	logFlag     = flag.String("log", "", "write stderr of the server to `file` instead of +lsplog window")
+	traceFlag   = flag.String("trace", "", "record messages between the server to `file`")
+	fmtFlag     = flag.String("fmt", "go", "format documents of comma separated `languages` before saving")
+)
+
+var (
+	// serverLog receives stderr of the server.
+	serverLog *ServerLog
+
+	// traceFile receives messages between the server if traceFlag is set.
+	traceFile io.Writer
 )
 
 func main() {
 	flag.Parse()
+	setFormatOnPut(*fmtFlag)
 
 	// This app watches all window.
 	acme.AutoExit(false)
 
-	conn, err := lsp.OpenCommand("gopls", "-v", "serve")
-	if err != nil {
-		log.Fatal(err)
+	if *logFlag != "" {
+		f, err := os.OpenFile(*logFlag, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		serverLog = NewServerLog(f)
+	} else {
+		serverLog = NewServerLog(NewLogWin())
 	}
-	c := lsp.NewClient(conn)
-	if err := initialize(c); err != nil {
+
+	if *traceFlag != "" {
+		f, err := os.OpenFile(*traceFlag, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		traceFile = f
+	}
+
+	var srv Server
+	if err := srv.Connect(); err != nil {
 		log.Fatal(err)
 	}
-	log.Fatal(start(c))
+	log.Fatal(start(&srv))
+}
+
+// openConn returns a connection to the server that is chosen by flags.
+func openConn() (io.ReadWriteCloser, error) {
+	conn, err := dialServer()
+	if err != nil {
+		return nil, err
+	}
+	if traceFile != nil {
+		return lsp.NewRecorder(conn, traceFile), nil
+	}
+	return conn, nil
+}
+
+func dialServer() (io.ReadWriteCloser, error) {
+	if *remoteFlag != "" {
+		return lsp.Dial(*remoteFlag)
+	}
+	cmd := exec.Command("gopls", "-v", "serve")
+	cmd.Stderr = serverLog
+	return lsp.StartCommand(cmd)
 }
 
 func initialize(c *lsp.Client) error {
-	r := c.Initialize(&lsp.InitializeParams{
+	params := &lsp.InitializeParams{
 		RootURI: c.URL("."),
-	})
+	}
+	params.Capabilities.TextDocument.Hover.ContentFormat = []string{
+		lsp.MarkupKindPlainText,
+	}
+	params.Capabilities.TextDocument.Declaration.LinkSupport = true
+	params.Capabilities.TextDocument.Definition.LinkSupport = true
+	params.Capabilities.TextDocument.TypeDefinition.LinkSupport = true
+	params.Capabilities.TextDocument.Implementation.LinkSupport = true
+	params.Capabilities.TextDocument.Rename.PrepareSupport = true
+	params.Capabilities.TextDocument.DocumentSymbol.HierarchicalDocumentSymbolSupport = true
+	codeAction := &params.Capabilities.TextDocument.CodeAction
+	codeAction.CodeActionLiteralSupport = &lsp.CodeActionLiteralSupport{}
+	codeAction.CodeActionLiteralSupport.CodeActionKind.ValueSet = []string{
+		lsp.CodeActionKindQuickFix,
+		lsp.CodeActionKindRefactor,
+		lsp.CodeActionKindRefactorExtract,
+		lsp.CodeActionKindRefactorInline,
+		lsp.CodeActionKindRefactorRewrite,
+		lsp.CodeActionKindSource,
+		lsp.CodeActionKindSourceOrganizeImports,
+	}
+	codeAction.DisabledSupport = true
+	codeAction.DataSupport = true
+	codeAction.ResolveSupport = &lsp.ResolveSupport{
+		Properties: []string{"edit"},
+	}
+	params.Capabilities.Workspace.ApplyEdit = true
+	params.Capabilities.Workspace.WorkspaceEdit.DocumentChanges = true
+	r := c.Initialize(params)
 	if err := r.Wait(); err != nil {
 		return err
 	}
@@ -41,3 +125,43 @@ func initialize(c *lsp.Client) error {
 	}
 	return nil
 }
+
+// handleRequest responds to requests from the server.
+func handleRequest(method string, params json.RawMessage) (interface{}, error) {
+	switch method {
+	case "workspace/configuration":
+		var p lsp.ConfigurationParams
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, &lsp.ResponseError{
+				Code:    lsp.ErrorCodeInvalidParams,
+				Message: err.Error(),
+			}
+		}
+		// we don't have any configurations; server will use default values.
+		return make([]interface{}, len(p.Items)), nil
+	case "client/registerCapability", "client/unregisterCapability":
+		return nil, nil
+	case "window/workDoneProgress/create":
+		return nil, nil
+	case "workspace/applyEdit":
+		var p lsp.ApplyWorkspaceEditParams
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, &lsp.ResponseError{
+				Code:    lsp.ErrorCodeInvalidParams,
+				Message: err.Error(),
+			}
+		}
+		if err := applyWorkspaceEdit(&p.Edit); err != nil {
+			return &lsp.ApplyWorkspaceEditResponse{
+				Applied:       false,
+				FailureReason: err.Error(),
+			}, nil
+		}
+		return &lsp.ApplyWorkspaceEditResponse{Applied: true}, nil
+	default:
+		return nil, &lsp.ResponseError{
+			Code:    lsp.ErrorCodeMethodNotFound,
+			Message: "method not found: " + method,
+		}
+	}
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"bytes"
+	"path"
+	"sync"
+
+	"9fans.net/go/acme"
+	"github.com/lufia/acme-lsp/outline"
+)
+
+// outputWinName returns the name of the window that shows results for file.
+func outputWinName(file, name string) string {
+	return path.Join(path.Dir(file), name)
+}
+
+var (
+	outputMu   sync.Mutex
+	outputWins = make(map[string]*acme.Win)
+)
+
+// lookupWin returns the window named name that is created by this process.
+// If the window doesn't exist, lookupWin returns nil.
+func lookupWin(name string) *acme.Win {
+	outputMu.Lock()
+	defer outputMu.Unlock()
+	return lookupWinLocked(name)
+}
+
+func lookupWinLocked(name string) *acme.Win {
+	w, ok := outputWins[name]
+	if !ok {
+		return nil
+	}
+	if err := w.Ctl("clean"); err != nil {
+		// the window was deleted by user
+		w.CloseFiles()
+		delete(outputWins, name)
+		return nil
+	}
+	return w
+}
+
+// openWin returns the window named name that is created by this process.
+// If the window doesn't exist, openWin creates new one.
+func openWin(name string) (*acme.Win, error) {
+	outputMu.Lock()
+	defer outputMu.Unlock()
+	if w := lookupWinLocked(name); w != nil {
+		return w, nil
+	}
+	w, err := acme.New()
+	if err != nil {
+		return nil, err
+	}
+	w.Name("%s", name)
+	outputWins[name] = w
+	return w, nil
+}
+
+// writeWin replaces the body of the window named name with text, then shows it.
+func writeWin(name, text string) (*acme.Win, error) {
+	w, err := updateWin(name, text)
+	if err != nil {
+		return nil, err
+	}
+	w.Ctl("show")
+	return w, nil
+}
+
+// updateWin is like writeWin but it don't raise the window.
+func updateWin(name, text string) (*acme.Win, error) {
+	w, err := openWin(name)
+	if err != nil {
+		return nil, err
+	}
+	w.Clear()
+	if _, err := w.Write("body", []byte(text)); err != nil {
+		return nil, err
+	}
+	w.Ctl("clean")
+	w.Addr("0")
+	w.Ctl("dot=addr")
+	return w, nil
+}
+
+// listWin is a window that shows entries line by line.
+// When an entry is clicked by 3 button, listWin calls its look function
+// with the index of the entry; commands in its tag are called
+// with the index of the entry at dot.
+type listWin struct {
+	name string
+	win  *acme.Win
+
+	mu   sync.Mutex
+	n    int // number of entries
+	look func(i int) error
+	cmds map[string]func(i int) error
+}
+
+var listWins = make(map[string]*listWin) // protected by outputMu
+
+// openListWin returns the list window named name that is created by this process.
+// If the window doesn't exist, openListWin creates new one that has tag.
+func openListWin(name, tag string) (*listWin, error) {
+	outputMu.Lock()
+	defer outputMu.Unlock()
+	if l, ok := listWins[name]; ok {
+		return l, nil
+	}
+	win, err := acme.New()
+	if err != nil {
+		return nil, err
+	}
+	win.Name("%s", name)
+	if tag != "" {
+		win.Fprintf("tag", "%s", tag)
+	}
+	l := &listWin{name: name, win: win}
+	listWins[name] = l
+	go l.watch()
+	return l, nil
+}
+
+// show replaces entries of l with lines, selects i-th entry, then shows l.
+// If look is nil, entries clicked by 3 button are looked up by acme.
+func (l *listWin) show(lines []string, i int, look func(i int) error, cmds map[string]func(i int) error) error {
+	l.mu.Lock()
+	l.n = len(lines)
+	l.look = look
+	l.cmds = cmds
+	l.mu.Unlock()
+
+	var buf bytes.Buffer
+	for _, s := range lines {
+		buf.WriteString(s + "\n")
+	}
+	l.win.Clear()
+	if _, err := l.win.Write("body", buf.Bytes()); err != nil {
+		return err
+	}
+	l.win.Ctl("clean")
+	l.win.Addr("%d", i+1)
+	l.win.Ctl("dot=addr")
+	l.win.Ctl("show")
+	return nil
+}
+
+func (l *listWin) watch() {
+	for e := range l.win.EventChan() {
+		if err := l.handleEvent(e); err != nil {
+			acme.Errf(l.name, "%v", err)
+		}
+	}
+	outputMu.Lock()
+	defer outputMu.Unlock()
+	if listWins[l.name] == l {
+		delete(listWins, l.name)
+	}
+}
+
+func (l *listWin) handleEvent(e *acme.Event) error {
+	l.mu.Lock()
+	n, look, cmds := l.n, l.look, l.cmds
+	l.mu.Unlock()
+
+	switch e.C2 {
+	case 'l', 'L':
+		if look == nil {
+			return l.win.WriteEvent(e)
+		}
+		i, err := l.lineAt(e.Q0)
+		if err != nil {
+			return err
+		}
+		if i >= n {
+			return nil
+		}
+		return look(i)
+	case 'x', 'X':
+		cmd, _ := parseCommand(e)
+		f, ok := cmds[cmd]
+		if !ok {
+			return l.win.WriteEvent(e)
+		}
+		l.win.Addr("0")
+		if err := l.win.Ctl("addr=dot"); err != nil {
+			return err
+		}
+		q0, _, err := l.win.ReadAddr()
+		if err != nil {
+			return err
+		}
+		i, err := l.lineAt(q0)
+		if err != nil {
+			return err
+		}
+		if i >= n {
+			return nil
+		}
+		return f(i)
+	}
+	return nil
+}
+
+// lineAt returns the line number, 0-origin, at the offset q in the window.
+func (l *listWin) lineAt(q int) (int, error) {
+	body, err := l.win.ReadAll("body")
+	if err != nil {
+		return 0, err
+	}
+	f, err := outline.NewFile(bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	addr, err := f.Addr(outline.Pos(q))
+	if err != nil {
+		return 0, err
+	}
+	return int(addr.Line), nil
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/lufia/acme-lsp/lsp"
+)
+
+const (
+	restartRetries  = 3
+	restartInterval = time.Second
+
+	// Restart gives up if the server crashed more than maxCrashes times in crashPeriod.
+	maxCrashes  = 5
+	crashPeriod = time.Minute
+)
+
+// Server supervises a connection to the language server.
+// When the connection is lost, Restart opens a new connection and initializes it.
+type Server struct {
+	mu sync.Mutex
+	c  *lsp.Client
+
+	crashes []time.Time // times of recent restarts
+}
+
+// Client returns the client connected to the current server.
+func (s *Server) Client() *lsp.Client {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.c
+}
+
+// Connect opens a connection to the server, then initializes it.
+func (s *Server) Connect() error {
+	conn, err := openConn()
+	if err != nil {
+		return err
+	}
+	c := lsp.NewClient(conn)
+	c.Handler = lsp.HandlerFunc(handleRequest)
+	watchEvents(c)
+	if err := initialize(c); err != nil {
+		c.Close()
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.c = c
+	return nil
+}
+
+// Restart closes the current connection and opens new one.
+// If the server crashes too often, for example on every didOpen,
+// Restart gives up restarting and returns an error.
+func (s *Server) Restart() error {
+	if c := s.Client(); c != nil {
+		c.Close()
+	}
+	if n := s.recordCrash(time.Now()); n > maxCrashes {
+		return fmt.Errorf("the server crashed %d times in %v; giving up", n, crashPeriod)
+	}
+	var err error
+	for i := 0; i < restartRetries; i++ {
+		if i > 0 {
+			time.Sleep(restartInterval)
+		}
+		if err = s.Connect(); err == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("can't restart the server: %w", err)
+}
+
+// recordCrash records a crash at t, then returns the number of crashes in crashPeriod.
+func (s *Server) recordCrash(t time.Time) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	a := s.crashes[:0]
+	for _, c := range s.crashes {
+		if t.Sub(c) < crashPeriod {
+			a = append(a, c)
+		}
+	}
+	s.crashes = append(a, t)
+	return len(s.crashes)
+}
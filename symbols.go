@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/lufia/acme-lsp/lsp"
+)
+
+var (
+	outlinesMu sync.Mutex
+	outlines   = make(map[string]string) // name of +outline window -> file
+)
+
+// ExecSymbols shows symbols defined in w in +outline window.
+func (w *Win) ExecSymbols() error {
+	name := outputWinName(w.file, "+outline")
+	outlinesMu.Lock()
+	outlines[name] = w.file
+	outlinesMu.Unlock()
+	s, err := w.readSymbols()
+	if err != nil {
+		return err
+	}
+	_, err = writeWin(name, s)
+	return err
+}
+
+// refreshSymbols updates +outline window if it shows symbols of w.
+func (w *Win) refreshSymbols() error {
+	name := outputWinName(w.file, "+outline")
+	outlinesMu.Lock()
+	file := outlines[name]
+	outlinesMu.Unlock()
+	if file != w.file || lookupWin(name) == nil {
+		return nil
+	}
+	s, err := w.readSymbols()
+	if err != nil {
+		return err
+	}
+	_, err = updateWin(name, s)
+	return err
+}
+
+// readSymbols returns the outline of symbols defined in w.
+func (w *Win) readSymbols() (string, error) {
+	result := w.srv.Client().DocumentSymbol(&lsp.DocumentSymbolParams{
+		TextDocument: w.DocumentID(),
+	})
+	ctx, cancel := newContext()
+	defer cancel()
+	if err := result.WaitContext(ctx); err != nil {
+		return "", err
+	}
+	if len(result.Symbols) == 0 && len(result.Information) == 0 {
+		return "", errors.New("no symbols")
+	}
+	var buf bytes.Buffer
+	file := path.Base(w.file)
+	writeDocumentSymbols(&buf, file, result.Symbols, 0)
+	for _, s := range result.Information {
+		depth := 0
+		if s.ContainerName != "" {
+			depth = 1
+		}
+		writeSymbol(&buf, depth, s.Kind, s.Name, file, s.Location.Range.Start.Line)
+	}
+	return buf.String(), nil
+}
+
+func writeDocumentSymbols(buf *bytes.Buffer, file string, symbols []lsp.DocumentSymbol, depth int) {
+	for _, s := range symbols {
+		writeSymbol(buf, depth, s.Kind, s.Name, file, s.SelectionRange.Start.Line)
+		writeDocumentSymbols(buf, file, s.Children, depth+1)
+	}
+}
+
+// writeSymbol writes a line that describes a symbol at line of file.
+// line is 0-origin.
+func writeSymbol(buf *bytes.Buffer, depth int, kind lsp.SymbolKind, name, file string, line int) {
+	fmt.Fprintf(buf, "%s%s %s\t%s:%d\n", strings.Repeat("\t", depth), kind, name, file, line+1)
+}
+
+// ExecSym shows symbols that match query in the workspace.
+func (w *Win) ExecSym(query string) error {
+	if query == "" {
+		return errors.New("usage: Sym query")
+	}
+	result := w.srv.Client().WorkspaceSymbol(&lsp.WorkspaceSymbolParams{
+		Query: query,
+	})
+	ctx, cancel := newContext()
+	defer cancel()
+	if err := result.WaitContext(ctx); err != nil {
+		return err
+	}
+	if len(result.Symbols) == 0 {
+		return fmt.Errorf("no symbols match %s", query)
+	}
+	name := outputWinName(w.file, "+symbols")
+	dir := path.Dir(name)
+	var buf bytes.Buffer
+	for _, s := range result.Symbols {
+		file := relPath(dir, s.Location.URI.String())
+		writeSymbol(&buf, 0, s.Kind, s.Name, file, s.Location.Range.Start.Line)
+	}
+	_, err := writeWin(name, buf.String())
+	return err
+}
+
+// relPath returns file relative to dir if file is placed under dir.
+// Otherwise it returns file as is.
+func relPath(dir, file string) string {
+	s, err := filepath.Rel(dir, file)
+	if err != nil || strings.HasPrefix(s, "..") {
+		return file
+	}
+	return s
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"path"
+
+	"github.com/lufia/acme-lsp/lsp"
+)
+
+// ExecTypes shows supertypes and subtypes of the type at the cursor in +types window.
+// For Go, supertypes are interfaces the type implements,
+// and subtypes are types that implement the interface.
+func (w *Win) ExecTypes() error {
+	params, err := w.cursorParams()
+	if err != nil {
+		return err
+	}
+	client := w.srv.Client()
+	result := client.PrepareTypeHierarchy(params)
+	ctx, cancel := newContext()
+	defer cancel()
+	if err := result.WaitContext(ctx); err != nil {
+		return err
+	}
+	if len(result.Items) == 0 {
+		return errors.New("no types at the cursor")
+	}
+
+	name := outputWinName(w.file, "+types")
+	dir := path.Dir(name)
+	var buf bytes.Buffer
+	writeItem := func(depth int, item *lsp.TypeHierarchyItem) {
+		file := relPath(dir, item.URI.String())
+		writeSymbol(&buf, depth, item.Kind, item.Name, file, item.SelectionRange.Start.Line)
+	}
+	for _, item := range result.Items {
+		supers := client.Supertypes(&lsp.TypeHierarchySupertypesParams{Item: item})
+		subs := client.Subtypes(&lsp.TypeHierarchySubtypesParams{Item: item})
+		if err := supers.WaitContext(ctx); err != nil {
+			return err
+		}
+		if err := subs.WaitContext(ctx); err != nil {
+			return err
+		}
+		writeItem(0, &item)
+		if len(supers.Items) > 0 {
+			buf.WriteString("\tsupertypes:\n")
+			for _, t := range supers.Items {
+				writeItem(2, &t)
+			}
+		}
+		if len(subs.Items) > 0 {
+			buf.WriteString("\tsubtypes:\n")
+			for _, t := range subs.Items {
+				writeItem(2, &t)
+			}
+		}
+	}
+	_, err = writeWin(name, buf.String())
+	return err
+}